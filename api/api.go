@@ -1,37 +1,70 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/cmd"
 	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
-	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/qdrant"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/jobs"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/store"
 )
 
 type Server struct {
 	cfg    *config.Config
 	cmd    *cmd.Command
+	jobs   *jobs.Manager
 	Router *chi.Mux
 }
 
 func New(cfg *config.Config) (*Server, error) {
-	db, err := qdrant.New(cfg.DatabaseURL)
+	db, err := cmd.OpenStore(context.Background(), cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	jobsFile := cfg.JobsFile
+	if jobsFile == "" {
+		jobsFile = filepath.Join(cfg.WorkDir, "jobs.json")
+	}
+
+	command, err := cmd.New(cfg, db)
+	if err != nil {
+		return nil, err
+	}
+
 	r := chi.NewRouter()
 	s := &Server{
 		cfg:    cfg,
-		cmd:    cmd.New(cfg, db),
+		cmd:    command,
 		Router: r,
 	}
 
+	s.jobs, err = jobs.New(jobsFile, cfg.JobWorkers, func(ctx context.Context, job jobs.Job, events cmd.EventFunc) ([]string, error) {
+		command, err := cmd.New(job.Options.Apply(cfg), db)
+		if err != nil {
+			return nil, err
+		}
+
+		if job.Kind == jobs.KindReindex {
+			return nil, command.Reindex(ctx, job.Input, events)
+		}
+
+		return command.Process(ctx, job.Input, events)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start job manager: %w", err)
+	}
+
 	s.setupMiddleware()
 	s.setupRoutes()
 
@@ -43,15 +76,30 @@ func (s *Server) setupMiddleware() {
 	s.Router.Use(middleware.RealIP)
 	s.Router.Use(middleware.Logger)
 	s.Router.Use(middleware.Recoverer)
-	s.Router.Use(middleware.Timeout(60 * time.Second))
 }
 
 func (s *Server) setupRoutes() {
 	s.Router.Route("/api", func(r chi.Router) {
-		r.Get("/health", s.handleHealth)
-		r.Post("/process", s.handleProcess)
-		r.Post("/search", s.handleSearch)
-		r.Post("/clean", s.handleClean)
+		// event streams stay open for as long as the job runs
+		r.Get("/jobs/{id}/events", s.handleJobEvents)
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Timeout(60 * time.Second))
+
+			r.Get("/health", s.handleHealth)
+			r.Post("/process", s.handleProcess)
+			r.Get("/jobs", s.handleListJobs)
+			r.Get("/jobs/{id}", s.handleGetJob)
+			r.Delete("/jobs/{id}", s.handleCancelJob)
+			r.Get("/videos", s.handleListVideos)
+			r.Get("/videos/{id}", s.handleGetVideo)
+			r.Delete("/videos", s.handleDeleteVideo)
+			r.Delete("/videos/{id}", s.handleDeleteVideo)
+			r.Post("/search", s.handleSearch)
+			r.Post("/facets", s.handleFacets)
+			r.Post("/reindex", s.handleReindex)
+			r.Post("/clean", s.handleClean)
+		})
 	})
 }
 
@@ -62,6 +110,7 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleProcess(w http.ResponseWriter, r *http.Request) {
 	type processRequest struct {
 		Url string `json:"url"`
+		config.Overrides
 	}
 
 	var req processRequest
@@ -75,22 +124,119 @@ func (s *Server) handleProcess(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := s.cmd.Process(r.Context(), req.Url)
+	job, err := s.jobs.Submit(jobs.KindProcess, req.Url, req.Overrides)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to process video")
+		writeError(w, http.StatusInternalServerError, "failed to queue video")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, nil)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *Server) handleReindex(w http.ResponseWriter, r *http.Request) {
+	type reindexRequest struct {
+		FromModel string `json:"from_model"`
+	}
+
+	var req reindexRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.FromModel == "" {
+		writeError(w, http.StatusBadRequest, "from_model is required")
+		return
+	}
+
+	job, err := s.jobs.Submit(jobs.KindReindex, req.FromModel, config.Overrides{})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to queue reindex")
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.jobs.List())
+}
+
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, err := s.jobs.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	job, err := s.jobs.Cancel(chi.URLParam(r, "id"))
+	if errors.Is(err, jobs.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	} else if errors.Is(err, jobs.ErrFinished) {
+		writeError(w, http.StatusConflict, "job already finished")
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to cancel job")
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *Server) handleJobEvents(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	updates, unsubscribe, err := s.jobs.Subscribe(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	defer unsubscribe()
+
+	stream, ok := newEventStream(w)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	keepalive := time.NewTicker(15 * time.Second)
+	defer keepalive.Stop()
+
+	var last jobs.Job
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			stream.comment("keepalive")
+		case job, ok := <-updates:
+			if !ok {
+				// the final snapshot may have been dropped for a slow client
+				if job, err := s.jobs.Get(id); err == nil && job.UpdatedAt != last.UpdatedAt {
+					stream.send(jobEventName(job), job)
+				}
+				return
+			}
+
+			last = job
+			stream.send(jobEventName(job), job)
+		}
+	}
 }
 
 func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 	type searchRequest struct {
 		Query string `json:"query"`
-		Limit int    `json:"limit,omitempty"`
+		cmd.QueryOptions
 	}
 
-	var req searchRequest
+	// fields missing from the body keep their configured defaults
+	req := searchRequest{QueryOptions: s.cmd.QueryOptions()}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid request body")
 		return
@@ -103,9 +249,25 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 
 	if req.Limit == 0 {
 		req.Limit = s.cfg.QueryLimit
+	} else if req.Limit < 0 {
+		writeError(w, http.StatusBadRequest, "limit must be positive")
+		return
+	}
+
+	if !slices.Contains(cmd.SearchModes, req.Mode) {
+		writeError(w, http.StatusBadRequest, "mode must be one of "+strings.Join(cmd.SearchModes, ", "))
+		return
 	}
 
-	res, err := s.cmd.Query(r.Context(), req.Query, req.Limit)
+	if !slices.Contains(cmd.RewriteModes, req.Rewrite) {
+		writeError(w, http.StatusBadRequest, "rewrite must be one of "+strings.Join(cmd.RewriteModes, ", "))
+		return
+	} else if req.Rewrite == cmd.RewriteMulti && req.Paraphrases < 1 {
+		writeError(w, http.StatusBadRequest, "paraphrases must be positive")
+		return
+	}
+
+	res, err := s.cmd.Query(r.Context(), req.Query, req.QueryOptions)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to query")
 		return
@@ -114,6 +276,80 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, res)
 }
 
+func (s *Server) handleFacets(w http.ResponseWriter, r *http.Request) {
+	type facetsRequest struct {
+		Filter store.Filter `json:"filter"`
+		Limit  int          `json:"limit,omitempty"`
+	}
+
+	req := facetsRequest{Limit: cmd.DefaultFacetLimit}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.Limit < 1 {
+		writeError(w, http.StatusBadRequest, "limit must be positive")
+		return
+	}
+
+	res, err := s.cmd.Facets(r.Context(), req.Filter, req.Limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to count facets")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) handleListVideos(w http.ResponseWriter, r *http.Request) {
+	res, err := s.cmd.Videos(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list videos")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) handleGetVideo(w http.ResponseWriter, r *http.Request) {
+	res, err := s.cmd.Video(r.Context(), chi.URLParam(r, "id"))
+	if errors.Is(err, store.ErrVideoNotFound) {
+		writeError(w, http.StatusNotFound, "video not found")
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to get video")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+// handleDeleteVideo deletes a video by the ID in the path, or by the url
+// query parameter since URLs do not fit in a path segment.
+func (s *Server) handleDeleteVideo(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		id = r.URL.Query().Get("url")
+	}
+
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "video id or url is required")
+		return
+	}
+
+	count, err := s.cmd.DeleteVideo(r.Context(), id)
+	if errors.Is(err, store.ErrVideoNotFound) {
+		writeError(w, http.StatusNotFound, "video not found")
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to delete video")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]uint64{"deleted_frames": count})
+}
+
 func (s *Server) handleClean(w http.ResponseWriter, r *http.Request) {
 	if err := s.cmd.Clean(r.Context()); err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to clean database")
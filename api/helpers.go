@@ -2,7 +2,10 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/jobs"
 )
 
 func writeJSON(w http.ResponseWriter, status int, data any) {
@@ -14,3 +17,48 @@ func writeJSON(w http.ResponseWriter, status int, data any) {
 func writeError(w http.ResponseWriter, status int, message string) {
 	writeJSON(w, status, map[string]string{"error": message})
 }
+
+type eventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newEventStream(w http.ResponseWriter) (*eventStream, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &eventStream{w: w, flusher: flusher}, true
+}
+
+func (s *eventStream) send(event string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload)
+	s.flusher.Flush()
+}
+
+func (s *eventStream) comment(text string) {
+	fmt.Fprintf(s.w, ": %s\n\n", text)
+	s.flusher.Flush()
+}
+
+// jobEventName names an event after the job stage, or its state once the
+// job is no longer running.
+func jobEventName(job jobs.Job) string {
+	if job.State == jobs.StateRunning && job.Stage != "" {
+		return string(job.Stage)
+	}
+
+	return string(job.State)
+}
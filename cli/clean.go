@@ -6,21 +6,24 @@ import (
 
 	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/cmd"
 	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
-	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/qdrant"
 	"github.com/urfave/cli/v2"
 )
 
 func CleanCommand(cfg *config.Config) *cli.Command {
 	return &cli.Command{
 		Name:  "clean",
-		Usage: "Clean processed video from database",
+		Usage: "Delete the processed videos of every embedding model from the database",
 		Action: func(c *cli.Context) error {
-			db, err := qdrant.New(cfg.DatabaseURL)
+			db, err := cmd.OpenStoreUnprobed(cfg)
 			if err != nil {
 				return fmt.Errorf("failed to connect to database: %w", err)
 			}
+			defer db.Close()
 
-			command := cmd.New(cfg, db)
+			command, err := cmd.New(cfg, db)
+			if err != nil {
+				return err
+			}
 			err = command.Clean(c.Context)
 			if err != nil {
 				return err
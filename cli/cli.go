@@ -1,7 +1,13 @@
 package cli
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
 	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/llm"
 	"github.com/urfave/cli/v2"
 )
 
@@ -14,6 +20,9 @@ func New() *cli.App {
 		Commands: []*cli.Command{
 			ProcessCommand(cfg),
 			QueryCommand(cfg),
+			FacetsCommand(cfg),
+			VideosCommand(cfg),
+			ReindexCommand(cfg),
 			CleanCommand(cfg),
 			ServeCommand(cfg),
 		},
@@ -24,10 +33,41 @@ func New() *cli.App {
 				Usage:       "Ollama server URL",
 				Destination: &cfg.OllamaURL,
 			},
+			&cli.StringFlag{
+				Name:        "openai-url",
+				Value:       "http://localhost:8000",
+				Usage:       "OpenAI-compatible server URL (vLLM, llama.cpp server, LocalAI, ...)",
+				EnvVars:     []string{"OPENAI_BASE_URL"},
+				Destination: &cfg.OpenAIURL,
+			},
+			&cli.StringFlag{
+				Name:        "openai-api-key",
+				Usage:       "API key for the OpenAI-compatible server",
+				EnvVars:     []string{"OPENAI_API_KEY"},
+				Destination: &cfg.OpenAIKey,
+			},
+			&cli.StringFlag{
+				Name:        "vision-backend",
+				Value:       llm.BackendOllama,
+				Usage:       fmt.Sprintf("Backend describing frames (%s)", strings.Join(llm.Backends, ", ")),
+				Destination: &cfg.VisionBackend,
+			},
+			&cli.StringFlag{
+				Name:        "text-backend",
+				Value:       llm.BackendOllama,
+				Usage:       fmt.Sprintf("Backend rewriting queries (%s)", strings.Join(llm.Backends, ", ")),
+				Destination: &cfg.TextBackend,
+			},
+			&cli.StringFlag{
+				Name:        "embedding-backend",
+				Value:       llm.BackendOllama,
+				Usage:       fmt.Sprintf("Backend embedding descriptions (%s)", strings.Join(llm.Backends, ", ")),
+				Destination: &cfg.EmbeddingBackend,
+			},
 			&cli.StringFlag{
 				Name:        "database-url",
 				Value:       "http://localhost:6334",
-				Usage:       "Vector database URL",
+				Usage:       "Vector database URL (a Qdrant server, or file:///path for an embedded database)",
 				Destination: &cfg.DatabaseURL,
 			},
 			&cli.StringFlag{
@@ -36,6 +76,65 @@ func New() *cli.App {
 				Usage:       "Description embedding model for retrieval",
 				Destination: &cfg.EmbeddingModel,
 			},
+			&cli.StringFlag{
+				Name:        "image-embedding-url",
+				Usage:       "OpenAI-compatible server embedding frame pixels and queries into one space (such as Infinity serving CLIP); disabled if empty",
+				EnvVars:     []string{"IMAGE_EMBEDDING_URL"},
+				Destination: &cfg.ImageEmbeddingURL,
+			},
+			&cli.StringFlag{
+				Name:        "image-embedding-model",
+				Value:       "openai/clip-vit-base-patch32",
+				Usage:       "Multimodal model embedding frame pixels",
+				Destination: &cfg.ImageEmbeddingModel,
+			},
+			&cli.IntFlag{
+				Name:        "workers",
+				Value:       2,
+				Usage:       "Number of frames to describe concurrently",
+				Destination: &cfg.Workers,
+			},
+			&cli.Float64Flag{
+				Name:        "rate-limit",
+				Usage:       "Maximum requests per second to each model host (0 for unlimited)",
+				Destination: &cfg.RateLimit,
+			},
+			&cli.StringFlag{
+				Name:        "work-dir",
+				Value:       filepath.Join(os.TempDir(), "llm-video-analyzer"),
+				Usage:       "Root directory for downloads and extracted frames",
+				Destination: &cfg.WorkDir,
+			},
+			&cli.StringFlag{
+				Name:        "s3-endpoint",
+				Usage:       "S3-compatible endpoint for s3:// sources (e.g. http://localhost:9000)",
+				EnvVars:     []string{"S3_ENDPOINT"},
+				Destination: &cfg.S3Endpoint,
+			},
+			&cli.StringFlag{
+				Name:        "s3-region",
+				Value:       "us-east-1",
+				Usage:       "S3 region for s3:// sources",
+				EnvVars:     []string{"AWS_REGION"},
+				Destination: &cfg.S3Region,
+			},
+			&cli.StringFlag{
+				Name:        "s3-access-key",
+				Usage:       "S3 access key for s3:// sources",
+				EnvVars:     []string{"AWS_ACCESS_KEY_ID"},
+				Destination: &cfg.S3AccessKey,
+			},
+			&cli.StringFlag{
+				Name:        "s3-secret-key",
+				Usage:       "S3 secret key for s3:// sources",
+				EnvVars:     []string{"AWS_SECRET_ACCESS_KEY"},
+				Destination: &cfg.S3SecretKey,
+			},
+			&cli.BoolFlag{
+				Name:        "debug",
+				Usage:       "Log extraction details (these interleave with the progress bar)",
+				Destination: &cfg.Debug,
+			},
 		},
 	}
 
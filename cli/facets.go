@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/cmd"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/store"
+	"github.com/urfave/cli/v2"
+)
+
+func FacetsCommand(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "facets",
+		Usage: "Count objects, scene types and colours of frames processed with --structured",
+		Flags: append([]cli.Flag{
+			&cli.IntFlag{
+				Name:  "limit",
+				Value: cmd.DefaultFacetLimit,
+				Usage: "Number of values to count per field",
+			},
+		}, filterFlags()...),
+		Action: func(c *cli.Context) error {
+			filter, err := searchFilter(c)
+			if err != nil {
+				return err
+			}
+
+			command, err := connect(cfg)
+			if err != nil {
+				return err
+			}
+
+			facets, err := command.Facets(c.Context, filter, c.Int("limit"))
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, field := range []struct {
+				name   string
+				counts []store.FacetCount
+			}{
+				{"OBJECT", facets.Objects},
+				{"SCENE", facets.SceneTypes},
+				{"COLOUR", facets.Colors},
+			} {
+				fmt.Fprintf(w, "%s\tFRAMES\n", field.name)
+				for _, fc := range field.counts {
+					fmt.Fprintf(w, "%s\t%d\n", fc.Value, fc.Count)
+				}
+				fmt.Fprintln(w)
+			}
+
+			return w.Flush()
+		},
+	}
+}
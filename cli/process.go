@@ -3,52 +3,168 @@ package cli
 import (
 	"fmt"
 	"log"
+	"os"
+	"strings"
 
 	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/cmd"
 	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
-	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/qdrant"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/llm"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/video"
 	"github.com/urfave/cli/v2"
 )
 
 func ProcessCommand(cfg *config.Config) *cli.Command {
 	return &cli.Command{
 		Name:      "process",
-		ArgsUsage: "<youtube-url>",
+		ArgsUsage: "<url|path>",
 		Usage:     "Process a video into database",
-		Flags: []cli.Flag{
-			&cli.IntFlag{
-				Name:        "sampling-interval",
-				Value:       2,
-				Usage:       "Frame sampling interval (seconds)",
-				Destination: &cfg.SamplingInterval,
-			},
-			&cli.StringFlag{
-				Name:        "sampling-model",
-				Value:       "llava:7b",
-				Usage:       "Frame sampling model for analysis",
-				Destination: &cfg.SamplingModel,
-			},
-		},
+		Flags:     processFlags(cfg),
 		Action: func(c *cli.Context) error {
-			url := c.Args().First()
-			if url == "" {
-				return fmt.Errorf("youtube url is required")
+			input := c.Args().First()
+			if input == "" {
+				return fmt.Errorf("url or path is required")
 			}
 
-			db, err := qdrant.New(cfg.DatabaseURL)
+			cfg.Extensions = c.StringSlice("extensions")
+			cfg.Tags = c.StringSlice("tag")
+
+			db, err := cmd.OpenStore(c.Context, cfg)
 			if err != nil {
-				return fmt.Errorf("failed to connect to database")
+				return fmt.Errorf("failed to connect to database: %w", err)
 			}
+			defer db.Close()
 
-			command := cmd.New(cfg, db)
-			_, err = command.Process(c.Context, url)
+			command, err := cmd.New(cfg, db)
+			if err != nil {
+				return err
+			}
+			urls, err := command.Process(c.Context, input, newProgressBar(os.Stderr).Render)
 			if err != nil {
 				return err
 			}
 
-			log.Println("successfully processed video")
+			log.Printf("successfully processed %d video(s)", len(urls))
 
 			return nil
 		},
 	}
 }
+
+// processFlags are shared by every command that processes videos.
+func processFlags(cfg *config.Config) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        "sampling-mode",
+			Value:       video.SamplingInterval,
+			Usage:       fmt.Sprintf("Frame sampling strategy (%s)", strings.Join(video.SamplingModes, ", ")),
+			Destination: &cfg.SamplingMode,
+		},
+		&cli.IntFlag{
+			Name:        "sampling-interval",
+			Value:       2,
+			Usage:       "Frame sampling interval (seconds)",
+			Destination: &cfg.SamplingInterval,
+		},
+		&cli.Float64Flag{
+			Name:        "scene-threshold",
+			Value:       0.3,
+			Usage:       "Scene change score (0-1) that selects a frame in scene and hybrid modes",
+			Destination: &cfg.SceneThreshold,
+		},
+		&cli.Float64Flag{
+			Name:        "min-gap",
+			Value:       1,
+			Usage:       "Minimum seconds between frames in hybrid mode",
+			Destination: &cfg.MinGap,
+		},
+		&cli.Float64Flag{
+			Name:        "max-gap",
+			Value:       10,
+			Usage:       "Maximum seconds between frames in hybrid mode",
+			Destination: &cfg.MaxGap,
+		},
+		&cli.StringFlag{
+			Name:        "sampling-model",
+			Value:       "llava:7b",
+			Usage:       "Frame sampling model for analysis",
+			Destination: &cfg.SamplingModel,
+		},
+		&cli.IntFlag{
+			Name:        "dedup-threshold",
+			Value:       5,
+			Usage:       "Maximum perceptual hash distance (0-64) for frames to count as duplicates, negative to disable",
+			Destination: &cfg.DedupThreshold,
+		},
+		&cli.BoolFlag{
+			Name:        "force",
+			Usage:       "Re-process videos that are already indexed",
+			Destination: &cfg.Force,
+		},
+		&cli.BoolFlag{
+			Name:        "subtitles",
+			Value:       true,
+			Usage:       "Fetch and index the subtitles of videos downloaded with yt-dlp",
+			Destination: &cfg.Subtitles,
+		},
+		&cli.StringFlag{
+			Name:        "subtitle-langs",
+			Value:       "en.*",
+			Usage:       "Subtitle languages to fetch, in yt-dlp --sub-langs syntax",
+			Destination: &cfg.SubtitleLangs,
+		},
+		&cli.Float64Flag{
+			Name:        "subtitle-window",
+			Value:       30,
+			Usage:       "Seconds of subtitles or transcript to embed together as one speech segment",
+			Destination: &cfg.SubtitleWindow,
+		},
+		&cli.BoolFlag{
+			Name:        "fusion",
+			Usage:       "Describe each frame together with its transcript and the previous frame, one frame at a time",
+			Destination: &cfg.Fusion,
+		},
+		&cli.StringFlag{
+			Name:        "frame-prompt",
+			Value:       llm.DefaultPrompt,
+			Usage:       "Frame description prompt: default, or a text/template file using .Timestamp, .Seconds, .Title, .Transcript and .Previous",
+			Destination: &cfg.FramePrompt,
+		},
+		&cli.BoolFlag{
+			Name:        "structured",
+			Usage:       "Have the vision model return structured JSON (summary, objects, people, on-screen text, scene type, colours) that can be filtered and faceted",
+			Destination: &cfg.Structured,
+		},
+		&cli.StringFlag{
+			Name:        "transcription-url",
+			Usage:       "Whisper-compatible transcription endpoint for videos without subtitles, e.g. http://localhost:8000/v1/audio/transcriptions",
+			EnvVars:     []string{"TRANSCRIPTION_URL"},
+			Destination: &cfg.TranscriptionURL,
+		},
+		&cli.StringFlag{
+			Name:        "transcription-model",
+			Value:       "whisper-1",
+			Usage:       "Model name sent to the transcription endpoint",
+			Destination: &cfg.TranscriptionModel,
+		},
+		&cli.Float64Flag{
+			Name:        "transcription-chunk",
+			Value:       300,
+			Usage:       "Seconds of audio sent per transcription request",
+			Destination: &cfg.TranscriptionChunk,
+		},
+		&cli.StringFlag{
+			Name:        "channel",
+			Usage:       "Channel to label processed videos with",
+			Destination: &cfg.Channel,
+		},
+		&cli.StringSliceFlag{
+			Name:  "tag",
+			Usage: "Tag to label processed videos with (repeatable)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "extensions",
+			Value: cli.NewStringSlice(video.DefaultExtensions...),
+			Usage: "Video file extensions to include when processing a directory",
+		},
+	}
+}
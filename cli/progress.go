@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/cmd"
+)
+
+const progressBarWidth = 30
+
+// progressBar renders processing events on a single, redrawn terminal line.
+type progressBar struct {
+	w     io.Writer
+	video string
+}
+
+func newProgressBar(w io.Writer) *progressBar {
+	return &progressBar{w: w}
+}
+
+func (p *progressBar) Render(e cmd.Event) {
+	if e.Err != nil {
+		fmt.Fprintf(p.w, "\r\033[Kwarning: %v\n", e.Err)
+	}
+
+	if e.Video != p.video && e.Video != "" {
+		fmt.Fprintf(p.w, "\r\033[K%s\n", e.Video)
+	}
+	p.video = e.Video
+
+	switch e.Stage {
+	case cmd.StageDescribing, cmd.StageStoring, cmd.StageReindexing:
+		fmt.Fprintf(p.w, "\r\033[K%-11s %s %d/%d frames", e.Stage, bar(e.FramesDone, e.FramesTotal), e.FramesDone, e.FramesTotal)
+		if e.ETA > 0 {
+			fmt.Fprintf(p.w, ", eta %s", e.ETA.Round(time.Second))
+		}
+	case cmd.StageSkipped:
+		fmt.Fprint(p.w, "\r\033[Kalready indexed, skipping (use --force to re-process)\n")
+	case cmd.StageDone, cmd.StageFailed:
+		fmt.Fprintf(p.w, "\r\033[K%-11s %s %d/%d frames\n", e.Stage, bar(e.FramesDone, e.FramesTotal), e.FramesDone, e.FramesTotal)
+	default:
+		fmt.Fprintf(p.w, "\r\033[K%s...", e.Stage)
+	}
+}
+
+func bar(done, total int) string {
+	filled := 0
+	if total > 0 {
+		filled = min(progressBarWidth, done*progressBarWidth/total)
+	}
+
+	return "[" + strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled) + "]"
+}
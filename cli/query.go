@@ -2,10 +2,15 @@ package cli
 
 import (
 	"fmt"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/cmd"
 	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
-	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/qdrant"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/llm"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/store"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/video"
 	"github.com/urfave/cli/v2"
 )
 
@@ -14,40 +19,59 @@ func QueryCommand(cfg *config.Config) *cli.Command {
 		Name:      "query",
 		Usage:     "Query processed videos",
 		ArgsUsage: "<query>",
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:        "query-model",
-				Value:       "llama3.2",
-				Usage:       "Query model for search",
-				Destination: &cfg.QueryModel,
-			},
-			&cli.IntFlag{
-				Name:        "limit",
-				Value:       3,
-				Usage:       "Number of results to return",
-				Destination: &cfg.QueryLimit,
-			},
-		},
+		Flags:     append(queryFlags(cfg), filterFlags()...),
 		Action: func(c *cli.Context) error {
 			query := c.Args().First()
 			if query == "" {
 				return fmt.Errorf("search query required")
 			}
 
-			db, err := qdrant.New(cfg.DatabaseURL)
+			db, err := cmd.OpenStore(c.Context, cfg)
+			if err != nil {
+				return fmt.Errorf("failed to connect to database: %w", err)
+			}
+			defer db.Close()
+
+			command, err := cmd.New(cfg, db)
+			if err != nil {
+				return err
+			}
+			opts := command.QueryOptions()
+			opts.Filter, err = searchFilter(c)
 			if err != nil {
-				return fmt.Errorf("failed to connect to database")
+				return err
 			}
 
-			command := cmd.New(cfg, db)
-			pts, err := command.Query(c.Context, query, cfg.QueryLimit)
+			res, err := command.Query(c.Context, query, opts)
 			if err != nil {
 				return err
 			}
 
-			for i, res := range pts {
+			if len(res.Rewrites) > 0 {
+				fmt.Println("Rewrites:")
+				for _, rewrite := range res.Rewrites {
+					fmt.Printf("  %s\n", rewrite)
+				}
+				fmt.Println()
+			}
+
+			for i, clip := range res.Clips {
 				fmt.Printf("Result: %d\n", i+1)
-				fmt.Printf("  Video: %s&t=%.0f\n", res.Url, res.Timestamp)
+				fmt.Printf("  Video: %s\n", timestampURL(clip.Url, clip.Timestamp))
+				if m := clip.Metadata; m != nil && m.Title != "" {
+					fmt.Printf("  Title: %s\n", m.Title)
+					if m.Uploader != "" {
+						fmt.Printf("  Channel: %s\n", m.Uploader)
+					}
+				}
+				fmt.Printf("  Clip: %s - %s (peak %s, score %.2f, %d frames)\n",
+					formatOffset(clip.Timestamp), formatOffset(clip.EndTimestamp),
+					formatOffset(clip.PeakTimestamp), clip.Score, clip.Frames)
+				if clip.Kind == video.KindSpeech {
+					fmt.Printf("  Speech: %s\n", clip.Description)
+				} else {
+					fmt.Printf("  Description: %s\n", clip.Description)
+				}
 				fmt.Println()
 			}
 
@@ -55,3 +79,248 @@ func QueryCommand(cfg *config.Config) *cli.Command {
 		},
 	}
 }
+
+// queryFlags are shared by every command that searches videos.
+func queryFlags(cfg *config.Config) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        "query-model",
+			Value:       "llama3.2",
+			Usage:       "Query model for search",
+			Destination: &cfg.QueryModel,
+		},
+		&cli.StringFlag{
+			Name:        "query-prompt",
+			Value:       llm.DefaultPrompt,
+			Usage:       "Query rewriting prompt: default, or a text/template file using .Query",
+			Destination: &cfg.QueryPrompt,
+		},
+		&cli.IntFlag{
+			Name:        "limit",
+			Value:       3,
+			Usage:       "Number of results to return",
+			Destination: &cfg.QueryLimit,
+		},
+		&cli.StringFlag{
+			Name:        "mode",
+			Value:       cmd.ModeDense,
+			Usage:       fmt.Sprintf("Search mode (%s)", strings.Join(cmd.SearchModes, ", ")),
+			Destination: &cfg.SearchMode,
+		},
+		&cli.StringFlag{
+			Name:        "rewrite",
+			Value:       cmd.RewriteHyDE,
+			Usage:       fmt.Sprintf("Query rewriting before embedding (%s)", strings.Join(cmd.RewriteModes, ", ")),
+			Destination: &cfg.QueryRewrite,
+		},
+		&cli.IntFlag{
+			Name:        "paraphrases",
+			Value:       3,
+			Usage:       "Number of descriptions searched with --rewrite multi",
+			Destination: &cfg.QueryParaphrases,
+		},
+		&cli.StringFlag{
+			Name:        "paraphrase-prompt",
+			Value:       llm.DefaultPrompt,
+			Usage:       "Paraphrasing prompt for --rewrite multi: default, or a text/template file using .Query and .Count",
+			Destination: &cfg.ParaphrasePrompt,
+		},
+		&cli.Float64Flag{
+			Name:        "clip-gap",
+			Value:       5,
+			Usage:       "Merge matching frames at most this many seconds apart into one clip",
+			Destination: &cfg.ClipGap,
+		},
+		&cli.IntFlag{
+			Name:        "clips-per-video",
+			Value:       1,
+			Usage:       "Clips per video to return before repeating a video (0 for no limit)",
+			Destination: &cfg.ClipsPerVideo,
+		},
+	}
+}
+
+// filterFlags restrict a single search, so unlike queryFlags they are not
+// shared with serve.
+func filterFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "search",
+			Value: "both",
+			Usage: "What to search: visual, speech or both",
+		},
+		&cli.StringSliceFlag{
+			Name:  "video",
+			Usage: "Only search the video with this ID or URL (repeatable)",
+		},
+		&cli.Float64Flag{
+			Name:  "from",
+			Usage: "Only search frames from this many seconds into each video",
+		},
+		&cli.Float64Flag{
+			Name:  "to",
+			Usage: "Only search frames up to this many seconds into each video",
+		},
+		&cli.StringFlag{
+			Name:  "indexed-after",
+			Usage: "Only search videos indexed on or after this date (YYYY-MM-DD or RFC 3339)",
+		},
+		&cli.StringFlag{
+			Name:  "indexed-before",
+			Usage: "Only search videos indexed on or before this date (YYYY-MM-DD or RFC 3339)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "channel",
+			Usage: "Only search videos of this channel (repeatable)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "tag",
+			Usage: "Only search videos with this tag (repeatable)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "object",
+			Usage: "Only search frames showing this object (repeatable, all must match; structured frames only)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "scene",
+			Usage: fmt.Sprintf("Only search frames of this scene type (repeatable: %s)", strings.Join(video.SceneTypes, ", ")),
+		},
+		&cli.StringSliceFlag{
+			Name:  "color",
+			Usage: "Only search frames with this dominant colour (repeatable)",
+		},
+		&cli.BoolFlag{
+			Name:  "has-text",
+			Usage: "Only search frames with on-screen text, or without it with --has-text=false",
+		},
+		&cli.IntFlag{
+			Name:  "min-people",
+			Usage: "Only search frames showing at least this many people",
+		},
+		&cli.IntFlag{
+			Name:  "max-people",
+			Usage: "Only search frames showing at most this many people",
+		},
+		&cli.Float64Flag{
+			Name:  "min-score",
+			Usage: "Drop vector search results below this similarity before rankings are fused",
+		},
+	}
+}
+
+func searchFilter(c *cli.Context) (store.Filter, error) {
+	filter := store.Filter{
+		Videos:   c.StringSlice("video"),
+		From:     c.Float64("from"),
+		To:       c.Float64("to"),
+		Channels: c.StringSlice("channel"),
+		Tags:     c.StringSlice("tag"),
+		MinScore: float32(c.Float64("min-score")),
+
+		Objects:    c.StringSlice("object"),
+		SceneTypes: c.StringSlice("scene"),
+		Colors:     c.StringSlice("color"),
+	}
+
+	if c.IsSet("has-text") {
+		filter.HasText = new(bool)
+		*filter.HasText = c.Bool("has-text")
+	}
+	if c.IsSet("min-people") {
+		filter.MinPeople = new(int)
+		*filter.MinPeople = c.Int("min-people")
+	}
+	if c.IsSet("max-people") {
+		filter.MaxPeople = new(int)
+		*filter.MaxPeople = c.Int("max-people")
+	}
+
+	switch search := c.String("search"); search {
+	case "both":
+	case video.KindVisual:
+		filter.Kinds = []string{video.KindVisual, video.KindSegment}
+	case video.KindSpeech:
+		filter.Kinds = []string{video.KindSpeech}
+	default:
+		return filter, fmt.Errorf("invalid --search %q: must be visual, speech or both", search)
+	}
+
+	var err error
+	if filter.IndexedAfter, err = parseDate(c.String("indexed-after"), false); err != nil {
+		return filter, fmt.Errorf("invalid --indexed-after: %w", err)
+	}
+	if filter.IndexedBefore, err = parseDate(c.String("indexed-before"), true); err != nil {
+		return filter, fmt.Errorf("invalid --indexed-before: %w", err)
+	}
+
+	return filter, nil
+}
+
+// parseDate parses an RFC 3339 time or a plain date. A plain date used as an
+// upper bound covers the whole day.
+func parseDate(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if endOfDay {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	return t, nil
+}
+
+// timestampURL links to timestamp within the video where the source supports
+// it, and returns other URLs unchanged.
+func timestampURL(rawURL string, timestamp float64) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	switch host := strings.ToLower(u.Hostname()); {
+	case u.Scheme == "file":
+		return fmt.Sprintf("%s#t=%.0f", rawURL, timestamp)
+	case isYouTubeHost(host):
+		sep := "?"
+		if u.RawQuery != "" {
+			sep = "&"
+		}
+		return fmt.Sprintf("%s%st=%.0f", rawURL, sep, timestamp)
+	default:
+		return rawURL
+	}
+}
+
+// isYouTubeHost reports whether host serves YouTube links, which take the
+// start time in a t query parameter.
+func isYouTubeHost(host string) bool {
+	for _, domain := range []string{"youtube.com", "youtu.be"} {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// formatOffset renders seconds into a video as m:ss or h:mm:ss.
+func formatOffset(seconds float64) string {
+	d := time.Duration(seconds) * time.Second
+	h, m, s := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+
+	return fmt.Sprintf("%d:%02d", m, s)
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/cmd"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
+	"github.com/urfave/cli/v2"
+)
+
+func ReindexCommand(cfg *config.Config) *cli.Command {
+	var fromModel string
+
+	return &cli.Command{
+		Name:  "reindex",
+		Usage: "Re-embed stored descriptions with the current embedding model",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "from-model",
+				Usage:       "Embedding model the descriptions were indexed with, or legacy to migrate the frames of earlier versions",
+				Required:    true,
+				Destination: &fromModel,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			db, err := cmd.OpenStore(c.Context, cfg)
+			if err != nil {
+				return fmt.Errorf("failed to connect to database: %w", err)
+			}
+			defer db.Close()
+
+			command, err := cmd.New(cfg, db)
+			if err != nil {
+				return err
+			}
+			if err := command.Reindex(c.Context, fromModel, newProgressBar(os.Stderr).Render); err != nil {
+				return err
+			}
+
+			log.Printf("reindexed %s frames with %s", fromModel, cfg.EmbeddingModel)
+
+			return nil
+		},
+	}
+}
@@ -14,15 +14,29 @@ func ServeCommand(cfg *config.Config) *cli.Command {
 	return &cli.Command{
 		Name:  "serve",
 		Usage: "Start the API server",
-		Flags: []cli.Flag{
+		Flags: append(append([]cli.Flag{
 			&cli.UintFlag{
 				Name:        "port",
 				Value:       8080,
 				Usage:       "Port to listen on",
 				Destination: &cfg.ServerPort,
 			},
-		},
+			&cli.IntFlag{
+				Name:        "job-workers",
+				Value:       1,
+				Usage:       "Number of processing jobs to run at once",
+				Destination: &cfg.JobWorkers,
+			},
+			&cli.StringFlag{
+				Name:        "jobs-file",
+				Usage:       "File to persist the job queue in (defaults to jobs.json in the work dir)",
+				Destination: &cfg.JobsFile,
+			},
+		}, processFlags(cfg)...), queryFlags(cfg)...),
 		Action: func(c *cli.Context) error {
+			cfg.Extensions = c.StringSlice("extensions")
+			cfg.Tags = c.StringSlice("tag")
+
 			server, err := api.New(cfg)
 			if err != nil {
 				return err
@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/cmd"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/video"
+	"github.com/urfave/cli/v2"
+)
+
+func VideosCommand(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "videos",
+		Usage: "Manage processed videos",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "List the videos processed with the embedding model",
+				Action: func(c *cli.Context) error {
+					command, err := connect(cfg)
+					if err != nil {
+						return err
+					}
+					defer command.Close()
+
+					videos, err := command.Videos(c.Context)
+					if err != nil {
+						return err
+					}
+
+					w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+					fmt.Fprintln(w, "ID\tFRAMES\tINDEXED\tURL")
+					for _, v := range videos {
+						fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", v.ID, v.Frames, formatTime(v.IndexedAt), strings.Join(v.URLs, ", "))
+					}
+
+					return w.Flush()
+				},
+			},
+			{
+				Name:      "show",
+				Usage:     "Show a processed video",
+				ArgsUsage: "<id|url>",
+				Action: func(c *cli.Context) error {
+					id := c.Args().First()
+					if id == "" {
+						return fmt.Errorf("video id or url is required")
+					}
+
+					command, err := connect(cfg)
+					if err != nil {
+						return err
+					}
+					defer command.Close()
+
+					v, err := command.Video(c.Context, id)
+					if err != nil {
+						return err
+					}
+
+					fmt.Printf("ID:               %s\n", v.ID)
+					fmt.Printf("URLs:             %s\n", strings.Join(v.URLs, ", "))
+					fmt.Printf("Frames:           %d\n", v.Frames)
+					fmt.Printf("Sampling models:  %s\n", strings.Join(v.SamplingModels, ", "))
+					fmt.Printf("Embedding models: %s\n", strings.Join(v.EmbeddingModels, ", "))
+					fmt.Printf("Indexed:          %s\n", formatTime(v.IndexedAt))
+
+					if m := v.Metadata; m != nil {
+						printMetadata(m)
+					}
+
+					return nil
+				},
+			},
+			{
+				Name:      "delete",
+				Usage:     "Delete a processed video from database",
+				ArgsUsage: "<id|url>",
+				Action: func(c *cli.Context) error {
+					id := c.Args().First()
+					if id == "" {
+						return fmt.Errorf("video id or url is required")
+					}
+
+					command, err := connect(cfg)
+					if err != nil {
+						return err
+					}
+					defer command.Close()
+
+					count, err := command.DeleteVideo(c.Context, id)
+					if err != nil {
+						return err
+					}
+
+					fmt.Printf("deleted %d frames\n", count)
+
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func connect(cfg *config.Config) (*cmd.Command, error) {
+	db, err := cmd.OpenStoreUnprobed(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	return cmd.New(cfg, db)
+}
+
+func printMetadata(m *video.Metadata) {
+	var duration, fps string
+	if m.Duration > 0 {
+		duration = formatOffset(m.Duration)
+	}
+	if m.FPS > 0 {
+		fps = fmt.Sprintf("%.2f", m.FPS)
+	}
+
+	fields := []struct{ name, value string }{
+		{"Title", m.Title},
+		{"Uploader", m.Uploader},
+		{"Uploaded", m.UploadDate},
+		{"Duration", duration},
+		{"Resolution", m.Resolution()},
+		{"FPS", fps},
+		{"Codec", m.Codec},
+	}
+
+	for _, f := range fields {
+		if f.value != "" {
+			fmt.Printf("%-18s%s\n", f.name+":", f.value)
+		}
+	}
+
+	for _, t := range m.Thumbnails {
+		fmt.Printf("%-18s%s\n", "Thumbnail:", t)
+	}
+
+	if len(m.Chapters) > 0 {
+		fmt.Println("Chapters:")
+		for _, ch := range m.Chapters {
+			fmt.Printf("  %s - %s  %s\n", formatOffset(ch.Start), formatOffset(ch.End), ch.Title)
+		}
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return t.Local().Format(time.DateTime)
+}
@@ -7,6 +7,7 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/qdrant/go-client v1.14.0
 	github.com/urfave/cli/v2 v2.27.6
+	google.golang.org/protobuf v1.34.2
 )
 
 require (
@@ -18,5 +19,4 @@ require (
 	golang.org/x/text v0.21.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20240827150818-7e3bb234dfed // indirect
 	google.golang.org/grpc v1.66.0 // indirect
-	google.golang.org/protobuf v1.34.2 // indirect
 )
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/store"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/video"
+)
+
+// clipCandidates is how many frames are fetched per requested clip, so there
+// are enough neighbours to merge and enough videos to diversify across.
+const clipCandidates = 10
+
+// Clip is a run of adjacent matching frames of one video. Timestamp and
+// EndTimestamp bound the run, while Kind, Score, Description and Analysis are
+// those of its best matching frame at PeakTimestamp. Score is a similarity
+// when a single ranking was searched, and the reciprocal rank fusion score
+// when several were fused.
+type Clip struct {
+	Kind          string          `json:"kind"`
+	Url           string          `json:"url"`
+	Timestamp     float64         `json:"timestamp"`
+	EndTimestamp  float64         `json:"end_timestamp"`
+	PeakTimestamp float64         `json:"peak_timestamp"`
+	Description   string          `json:"description"`
+	Score         float32         `json:"score"`
+	Frames        int             `json:"frames"`
+	Metadata      *video.Metadata `json:"metadata,omitempty"`
+	Analysis      *video.Analysis `json:"analysis,omitempty"`
+}
+
+// groupClips merges hits of the same video that are at most gap seconds
+// apart into clips, ordered by score.
+func groupClips(hits []store.SearchResult, gap float64) []Clip {
+	byVideo := map[string][]store.SearchResult{}
+	for _, hit := range hits {
+		byVideo[hit.Url] = append(byVideo[hit.Url], hit)
+	}
+
+	var clips []Clip
+	for _, hits := range byVideo {
+		slices.SortFunc(hits, func(a, b store.SearchResult) int {
+			return cmp.Compare(a.Timestamp, b.Timestamp)
+		})
+
+		var clip *Clip
+		for _, hit := range hits {
+			if clip != nil && hit.Timestamp-clip.EndTimestamp <= gap {
+				clip.EndTimestamp = max(clip.EndTimestamp, hit.Timestamp, hit.EndTimestamp)
+				clip.Frames++
+				if hit.Score > clip.Score {
+					clip.Kind = hit.Kind
+					clip.PeakTimestamp = hit.Timestamp
+					clip.Description = hit.Description
+					clip.Score = hit.Score
+					clip.Analysis = hit.Analysis
+				}
+				continue
+			}
+
+			if clip != nil {
+				clips = append(clips, *clip)
+			}
+			clip = &Clip{
+				Kind:          hit.Kind,
+				Url:           hit.Url,
+				Timestamp:     hit.Timestamp,
+				EndTimestamp:  max(hit.Timestamp, hit.EndTimestamp),
+				PeakTimestamp: hit.Timestamp,
+				Description:   hit.Description,
+				Score:         hit.Score,
+				Frames:        1,
+				Metadata:      hit.Metadata,
+				Analysis:      hit.Analysis,
+			}
+		}
+
+		clips = append(clips, *clip)
+	}
+
+	slices.SortFunc(clips, func(a, b Clip) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+
+	return clips
+}
+
+// diversify picks up to limit clips by score while allowing at most perVideo
+// clips of any one video, only topping up with further clips of the same
+// videos when there are not enough others. A non-positive perVideo disables
+// the cap.
+func diversify(clips []Clip, limit, perVideo int) []Clip {
+	if perVideo < 1 || len(clips) <= limit {
+		return clips[:min(len(clips), limit)]
+	}
+
+	res := make([]Clip, 0, limit)
+	picked := make([]bool, len(clips))
+	counts := map[string]int{}
+	for i, clip := range clips {
+		if len(res) == limit {
+			break
+		} else if counts[clip.Url] >= perVideo {
+			continue
+		}
+
+		res = append(res, clip)
+		picked[i] = true
+		counts[clip.Url]++
+	}
+
+	for i, clip := range clips {
+		if len(res) == limit {
+			break
+		} else if !picked[i] {
+			res = append(res, clip)
+		}
+	}
+
+	slices.SortStableFunc(res, func(a, b Clip) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+
+	return res
+}
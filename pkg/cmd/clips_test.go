@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/store"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/video"
+)
+
+func hit(url string, timestamp float64, score float32) store.SearchResult {
+	return store.SearchResult{
+		Kind:         video.KindVisual,
+		Url:          url,
+		Timestamp:    timestamp,
+		EndTimestamp: timestamp,
+		Score:        score,
+	}
+}
+
+func TestGroupClips(t *testing.T) {
+	speech := hit("a", 4, 0.95)
+	speech.Kind = video.KindSpeech
+	speech.EndTimestamp = 9
+
+	noEnd := hit("a", 6, 0.7)
+	noEnd.EndTimestamp = 0
+
+	tests := []struct {
+		name string
+		hits []store.SearchResult
+		gap  float64
+		want []Clip
+	}{
+		{
+			name: "no hits",
+			gap:  5,
+		},
+		{
+			name: "merges neighbours",
+			hits: []store.SearchResult{hit("a", 10, 0.5), hit("a", 2, 0.9), hit("a", 6, 0.7)},
+			gap:  5,
+			want: []Clip{
+				{Kind: video.KindVisual, Url: "a", Timestamp: 2, EndTimestamp: 10, PeakTimestamp: 2, Score: 0.9, Frames: 3},
+			},
+		},
+		{
+			name: "splits at the gap",
+			hits: []store.SearchResult{hit("a", 1, 0.5), hit("a", 20, 0.9)},
+			gap:  5,
+			want: []Clip{
+				{Kind: video.KindVisual, Url: "a", Timestamp: 20, EndTimestamp: 20, PeakTimestamp: 20, Score: 0.9, Frames: 1},
+				{Kind: video.KindVisual, Url: "a", Timestamp: 1, EndTimestamp: 1, PeakTimestamp: 1, Score: 0.5, Frames: 1},
+			},
+		},
+		{
+			name: "keeps videos apart",
+			hits: []store.SearchResult{hit("a", 1, 0.5), hit("b", 2, 0.6)},
+			gap:  5,
+			want: []Clip{
+				{Kind: video.KindVisual, Url: "b", Timestamp: 2, EndTimestamp: 2, PeakTimestamp: 2, Score: 0.6, Frames: 1},
+				{Kind: video.KindVisual, Url: "a", Timestamp: 1, EndTimestamp: 1, PeakTimestamp: 1, Score: 0.5, Frames: 1},
+			},
+		},
+		{
+			name: "extends to merged hits without an end",
+			hits: []store.SearchResult{hit("a", 2, 0.9), noEnd},
+			gap:  5,
+			want: []Clip{
+				{Kind: video.KindVisual, Url: "a", Timestamp: 2, EndTimestamp: 6, PeakTimestamp: 2, Score: 0.9, Frames: 2},
+			},
+		},
+		{
+			name: "extends to the end of speech",
+			hits: []store.SearchResult{hit("a", 1, 0.5), speech, hit("a", 12, 0.4)},
+			gap:  3,
+			want: []Clip{
+				{Kind: video.KindSpeech, Url: "a", Timestamp: 1, EndTimestamp: 12, PeakTimestamp: 4, Score: 0.95, Frames: 3},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := groupClips(tt.hits, tt.gap)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groupClips = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiversify(t *testing.T) {
+	clips := []Clip{
+		{Url: "a", Score: 0.9},
+		{Url: "a", Score: 0.8},
+		{Url: "b", Score: 0.7},
+		{Url: "a", Score: 0.6},
+	}
+
+	tests := []struct {
+		name     string
+		limit    int
+		perVideo int
+		want     []float32
+	}{
+		{name: "no cap", limit: 3, want: []float32{0.9, 0.8, 0.7}},
+		{name: "one per video", limit: 2, perVideo: 1, want: []float32{0.9, 0.7}},
+		{name: "tops up", limit: 3, perVideo: 1, want: []float32{0.9, 0.8, 0.7}},
+		{name: "limit above clips", limit: 10, perVideo: 1, want: []float32{0.9, 0.8, 0.7, 0.6}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []float32
+			for _, clip := range diversify(clips, tt.limit, tt.perVideo) {
+				got = append(got, clip.Score)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diversify scores = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
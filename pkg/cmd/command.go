@@ -1,86 +1,448 @@
 package cmd
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"os"
+	"path/filepath"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
-	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/ollama"
-	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/qdrant"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/llm"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/store"
 	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/video"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/whisper"
 )
 
+const storeBatchSize = 32
+
 type Command struct {
-	cfg *config.Config
-	db  *qdrant.Client
+	cfg     *config.Config
+	db      store.VectorStore
+	models  llm.Models
+	prompts llm.Prompts
 }
 
-const downloadPath = "/tmp/llm-video-analyzer"
+func New(cfg *config.Config, db store.VectorStore) (*Command, error) {
+	models, err := llm.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	prompts, err := llm.LoadPrompts(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-func New(cfg *config.Config, db *qdrant.Client) *Command {
 	return &Command{
-		cfg: cfg,
-		db:  db,
+		cfg:     cfg,
+		db:      db,
+		models:  models,
+		prompts: prompts,
+	}, nil
+}
+
+// Close closes the vector store of the command.
+func (c *Command) Close() error {
+	return c.db.Close()
+}
+
+func (c *Command) Process(ctx context.Context, input string, events EventFunc) ([]string, error) {
+	t := &tracker{emit: events}
+
+	urls, err := c.process(ctx, input, t)
+	t.finish(err)
+
+	return urls, err
+}
+
+func (c *Command) process(ctx context.Context, input string, t *tracker) ([]string, error) {
+	root := input
+	if video.IsLocal(input) {
+		path, err := video.FilePath(input)
+		if err != nil {
+			return nil, err
+		}
+		root = path
+	} else if _, err := os.Stat(input); err != nil {
+		url, err := c.processURL(ctx, input, t)
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{url}, nil
 	}
+
+	paths, err := video.Find(root, c.cfg.Extensions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find videos: %w", err)
+	} else if len(paths) == 0 {
+		return nil, fmt.Errorf("no videos found in %s", root)
+	}
+
+	var (
+		urls []string
+		errs []error
+	)
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return urls, err
+		}
+
+		url, err := video.FileURL(path)
+		if err == nil {
+			url, err = c.processURL(ctx, url, t)
+		}
+		if err != nil {
+			t.fail(fmt.Errorf("skipping video %s: %w", path, err))
+			errs = append(errs, err)
+			continue
+		}
+
+		urls = append(urls, url)
+	}
+
+	if len(urls) == 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return urls, nil
 }
 
-func (c *Command) Process(ctx context.Context, url string) (string, error) {
-	d := video.NewYouTubeDownloader(downloadPath)
+func (c *Command) processURL(ctx context.Context, url string, t *tracker) (string, error) {
+	// local files are always hashed, so only remote videos are skipped early
+	if !c.cfg.Force && !video.IsLocal(url) {
+		indexed, err := c.db.IsIndexed(ctx, "url", url, c.cfg.SamplingModel, c.cfg.EmbeddingModel)
+		if err != nil {
+			return "", fmt.Errorf("failed to check index: %w", err)
+		} else if indexed {
+			t.enter(StageSkipped, url)
+			return url, nil
+		}
+	}
+
+	jobDir := filepath.Join(c.cfg.WorkDir, "downloads", uuid.NewString())
+	defer os.RemoveAll(jobDir)
+
+	d, err := video.NewDownloader(c.cfg, url, jobDir)
+	if err != nil {
+		return "", err
+	}
+
+	t.enter(StageDownloading, url)
 
 	path, err := d.Download(ctx, url)
 	if err != nil {
 		return "", err
 	}
-	defer os.Remove(path)
 
-	v, err := video.New(path)
+	dl := download{path: path}
+	if src, ok := d.(video.MetadataSource); ok {
+		dl.info = src.Metadata()
+	}
+	if src, ok := d.(video.SubtitleSource); ok {
+		dl.subtitles = src.Subtitles()
+	}
+
+	if err := c.index(ctx, url, dl, t); err != nil {
+		return "", err
+	}
+
+	return url, nil
+}
+
+// download is a fetched video along with what its source knew about it.
+type download struct {
+	path      string
+	info      *video.Metadata
+	subtitles string
+}
+
+// index extracts, describes and stores the frames of a downloaded video, then
+// the segments of its speech.
+func (c *Command) index(ctx context.Context, url string, dl download, t *tracker) error {
+	v, err := video.New(dl.path, c.cfg.WorkDir)
 	if err != nil {
-		return "", fmt.Errorf("failed to initialize video: %w", err)
+		return fmt.Errorf("failed to initialize video: %w", err)
+	}
+
+	if err := v.Lock(ctx); err != nil {
+		return fmt.Errorf("failed to lock video: %w", err)
 	}
+	defer v.Unlock()
+
+	if c.cfg.Force {
+		if err := c.db.DeleteFrames(ctx, v.ID, c.cfg.SamplingModel, c.cfg.EmbeddingModel); err != nil {
+			return fmt.Errorf("failed to delete indexed frames: %w", err)
+		}
+	} else {
+		indexed, err := c.db.IsIndexed(ctx, "video_id", v.ID, c.cfg.SamplingModel, c.cfg.EmbeddingModel)
+		if err != nil {
+			return fmt.Errorf("failed to check index: %w", err)
+		} else if indexed {
+			// local videos are keyed by content, so a moved file is only
+			// pointed at its new location
+			if video.IsLocal(url) {
+				if err := c.db.Relocate(ctx, v.ID, url); err != nil {
+					return fmt.Errorf("failed to relocate video: %w", err)
+				}
+			}
 
-	if err := v.Extract(c.cfg.SamplingInterval); err != nil {
-		return "", fmt.Errorf("frame extraction failed: %w", err)
+			t.enter(StageSkipped, url)
+			return nil
+		}
+	}
+
+	t.enter(StageExtracting, url)
+
+	if err := v.Extract(ctx, c.cfg); err != nil {
+		return fmt.Errorf("frame extraction failed: %w", err)
 	}
 	defer v.Cleanup()
 
+	if err := v.Deduplicate(c.cfg.DedupThreshold); err != nil {
+		return fmt.Errorf("frame deduplication failed: %w", err)
+	}
+
+	// speech comes first so fused frame descriptions can draw on it
+	cues, err := c.speech(ctx, url, v, dl, t)
+	if err != nil {
+		t.fail(fmt.Errorf("skipping speech: %w", err))
+	}
+
+	if c.cfg.Fusion {
+		video.AttachTranscript(v.Frames, cues, time.Duration(c.cfg.SubtitleWindow*float64(time.Second)))
+	}
+
+	t.extracted(len(v.Frames))
+
+	meta := c.metadata(ctx, v, dl.info, t)
+
+	src := store.Source{
+		VideoID:        v.ID,
+		URL:            url,
+		SamplingModel:  c.cfg.SamplingModel,
+		EmbeddingModel: c.cfg.EmbeddingModel,
+		Channel:        cmp.Or(c.cfg.Channel, meta.Uploader),
+		Tags:           c.cfg.Tags,
+		Metadata:       meta,
+		PromptTemplate: c.prompts.Frame.Name,
+		PromptHash:     c.prompts.Frame.Hash,
+		FramesTotal:    len(v.Frames),
+		IndexedAt:      time.Now(),
+	}
+
 	for i := range v.Frames {
-		frame := &v.Frames[i]
+		v.Frames[i].Title = meta.Title
+	}
+
+	var (
+		batch  []*video.Frame
+		stored int
+		failed bool
+	)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		if err := c.store(ctx, src, batch, t); err != nil {
+			t.fail(err)
+			failed = true
+		} else {
+			stored += len(batch)
+		}
+		batch = batch[:0]
+	}
+
+	err = video.ProcessAll(ctx, c.cfg, c.models, c.prompts.Frame, v.Frames, c.cfg.Workers, func(frame *video.Frame, err error) {
+		if err != nil {
+			t.processed(fmt.Errorf("skipping frame at %v: %w", frame.Timestamp, err))
+			return
+		}
+
+		t.processed(nil)
+
+		batch = append(batch, frame)
+		if len(batch) >= storeBatchSize {
+			flush()
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	flush()
+
+	if len(cues) > 0 {
+		if err := c.indexSpeech(ctx, src, cues, t); err != nil {
+			return err
+		}
+	}
+
+	// frames that could not be described are not retried on the next run,
+	// but frames and speech that could not be stored are
+	if !failed && stored > 0 {
+		if err := c.db.MarkIndexed(ctx, src, stored); err != nil {
+			t.fail(fmt.Errorf("failed to mark video indexed: %w", err))
+		}
+	}
+
+	return nil
+}
+
+// speech returns what is said in the video: its subtitles if the source had
+// any, otherwise a transcript if a transcription endpoint is configured.
+func (c *Command) speech(ctx context.Context, url string, v *video.Video, dl download, t *tracker) ([]video.Cue, error) {
+	if dl.subtitles != "" {
+		return video.ParseSubtitles(dl.subtitles)
+	} else if c.cfg.TranscriptionURL == "" {
+		return nil, nil
+	}
 
-		if err := frame.Process(ctx, c.cfg); err != nil {
-			log.Printf("skipping frame %s: %v", frame.Path, err)
+	t.enter(StageTranscribing, url)
+
+	chunks, err := v.ExtractAudio(ctx, time.Duration(c.cfg.TranscriptionChunk*float64(time.Second)))
+	if err != nil {
+		return nil, fmt.Errorf("audio extraction failed: %w", err)
+	}
+
+	t.queued(len(chunks))
+
+	// a failed chunk only leaves a gap in the transcript
+	var cues []video.Cue
+	for _, chunk := range chunks {
+		res, err := whisper.Transcribe(ctx, c.cfg, chunk.Path, chunk.Offset)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		} else if err != nil {
+			t.processed(fmt.Errorf("skipping audio at %v: %w", chunk.Offset, err))
 			continue
 		}
 
-		if err := c.db.Store(ctx, url, frame); err != nil {
-			log.Printf("failed to store frame %s: %v", frame.Path, err)
+		t.processed(nil)
+		cues = append(cues, res...)
+	}
+
+	return cues, nil
+}
+
+// indexSpeech embeds cues in windows of speech and stores them alongside the
+// visual frames of src.
+func (c *Command) indexSpeech(ctx context.Context, src store.Source, cues []video.Cue, t *tracker) error {
+	src.PromptTemplate, src.PromptHash = "", ""
+
+	segments := video.ChunkCues(cues, time.Duration(c.cfg.SubtitleWindow*float64(time.Second)))
+	t.extracted(len(segments))
+
+	var batch []*video.Frame
+	for i := range segments {
+		seg := &segments[i]
+
+		embedding, err := c.models.Embedding.Embed(ctx, seg.Description)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		} else if err != nil {
+			t.processed(fmt.Errorf("skipping speech at %v: %w", seg.Timestamp, err))
 			continue
 		}
+		seg.Embedding = embedding
+
+		t.processed(nil)
+
+		batch = append(batch, seg)
+		if len(batch) >= storeBatchSize {
+			if err := c.store(ctx, src, batch, t); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
 	}
 
-	return url, nil
+	if len(batch) > 0 {
+		return c.store(ctx, src, batch, t)
+	}
+
+	return nil
+}
+
+// store saves a batch of frames, showing the storing stage meanwhile.
+func (c *Command) store(ctx context.Context, src store.Source, batch []*video.Frame, t *tracker) error {
+	t.enter(StageStoring, src.URL)
+	defer t.enter(StageDescribing, src.URL)
+
+	if err := c.db.Store(ctx, src, batch); err != nil {
+		return fmt.Errorf("failed to store %d frames: %w", len(batch), err)
+	}
+
+	return nil
 }
 
-func (c *Command) Query(ctx context.Context, query string, limit int) ([]qdrant.SearchResult, error) {
-	desc, err := ollama.GetDescriptionFromQuery(ctx, c.cfg, query)
+// metadata builds the catalog record of v from what its source reported and
+// what ffprobe finds in the file. Probe failures only cost the technical
+// fields, so they are reported without failing the video.
+func (c *Command) metadata(ctx context.Context, v *video.Video, info *video.Metadata, t *tracker) *video.Metadata {
+	meta := &video.Metadata{}
+	if info != nil {
+		*meta = *info
+	}
+
+	probed, err := video.Probe(ctx, v.Path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get description: %w", err)
+		t.fail(fmt.Errorf("failed to probe video: %w", err))
 	}
+	meta.Merge(probed)
+
+	return meta
+}
+
+// Reindex re-embeds every description stored for fromModel with the
+// configured embedding model, so switching models does not require
+// describing every video again.
+func (c *Command) Reindex(ctx context.Context, fromModel string, events EventFunc) error {
+	t := &tracker{emit: events}
 
-	embedding, err := ollama.GetTextEmbedding(ctx, c.cfg, desc)
+	err := c.db.Reindex(ctx, fromModel, c.models.Embedding.Embed, t.reindexed)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get embedding: %w", err)
+		err = fmt.Errorf("failed to reindex: %w", err)
 	}
+	t.finish(err)
 
-	pts, err := c.db.Search(ctx, embedding, uint64(limit))
+	return err
+}
+
+// DefaultFacetLimit is the number of values counted per field by default.
+const DefaultFacetLimit = 20
+
+// Facets counts the structured fields of the frames matching filter, so a
+// search can be narrowed down by them.
+func (c *Command) Facets(ctx context.Context, filter store.Filter, limit int) (*store.Facets, error) {
+	res, err := c.db.Facets(ctx, filter, limit)
 	if err != nil {
-		return nil, fmt.Errorf("search failed: %w", err)
-	} else if len(pts) == 0 {
-		return nil, fmt.Errorf("no results found")
+		return nil, fmt.Errorf("failed to count facets: %w", err)
 	}
 
-	return pts, nil
+	return res, nil
+}
+
+func (c *Command) Videos(ctx context.Context) ([]store.VideoSummary, error) {
+	res, err := c.db.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list videos: %w", err)
+	}
+
+	return res, nil
+}
+
+func (c *Command) Video(ctx context.Context, idOrURL string) (*store.VideoSummary, error) {
+	return c.db.Get(ctx, idOrURL)
+}
+
+func (c *Command) DeleteVideo(ctx context.Context, idOrURL string) (uint64, error) {
+	return c.db.Delete(ctx, idOrURL)
 }
 
 func (c *Command) Clean(ctx context.Context) error {
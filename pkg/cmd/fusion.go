@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/store"
+)
+
+// rrfK damps the weight of the top ranks in reciprocal rank fusion. 60 is the
+// value from the original paper and works well without tuning.
+const rrfK = 60
+
+// fuseRanks merges ranked result lists with reciprocal rank fusion, so lists
+// whose scores are not comparable, such as searches of different vectors, can
+// be combined. The scores of the fused results are their fusion scores.
+func fuseRanks(lists ...[]store.SearchResult) []store.SearchResult {
+	if len(lists) == 1 {
+		return lists[0]
+	}
+
+	var (
+		res   []store.SearchResult
+		index = map[string]int{}
+	)
+	for _, list := range lists {
+		for rank, hit := range list {
+			score := float32(1) / float32(rrfK+rank+1)
+
+			key := fmt.Sprintf("%s|%s|%g", hit.Url, hit.Kind, hit.Timestamp)
+			if i, ok := index[key]; ok {
+				res[i].Score += score
+				continue
+			}
+
+			index[key] = len(res)
+			hit.Score = score
+			res = append(res, hit)
+		}
+	}
+
+	slices.SortStableFunc(res, func(a, b store.SearchResult) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+
+	return res
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/store"
+)
+
+// hitKeys identifies results by their video and timestamp.
+func hitKeys(res []store.SearchResult) []string {
+	var keys []string
+	for _, r := range res {
+		keys = append(keys, fmt.Sprintf("%s@%g", r.Url, r.Timestamp))
+	}
+
+	return keys
+}
+
+func TestFuseRanks(t *testing.T) {
+	tests := []struct {
+		name  string
+		lists [][]store.SearchResult
+		want  []string
+	}{
+		{
+			name:  "single list is unchanged",
+			lists: [][]store.SearchResult{{hit("a", 1, 0.9), hit("b", 1, 0.2)}},
+			want:  []string{"a@1", "b@1"},
+		},
+		{
+			name: "hits in both lists win",
+			lists: [][]store.SearchResult{
+				{hit("a", 1, 0.9), hit("b", 1, 0.8)},
+				{hit("c", 1, 30), hit("b", 1, 20)},
+			},
+			want: []string{"b@1", "a@1", "c@1"},
+		},
+		{
+			name: "ties keep list order",
+			lists: [][]store.SearchResult{
+				{hit("a", 1, 0.9)},
+				{hit("c", 1, 30)},
+			},
+			want: []string{"a@1", "c@1"},
+		},
+		{
+			name: "other timestamps are other hits",
+			lists: [][]store.SearchResult{
+				{hit("a", 1, 0.9), hit("a", 2, 0.8)},
+				{hit("a", 2, 30)},
+			},
+			want: []string{"a@2", "a@1"},
+		},
+		{
+			name: "empty list",
+			lists: [][]store.SearchResult{
+				nil,
+				{hit("a", 1, 0.9)},
+			},
+			want: []string{"a@1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hitKeys(fuseRanks(tt.lists...)); !slices.Equal(got, tt.want) {
+				t.Errorf("fuseRanks = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFuseRanksScores(t *testing.T) {
+	res := fuseRanks(
+		[]store.SearchResult{hit("a", 1, 0.9), hit("b", 1, 0.8)},
+		[]store.SearchResult{hit("b", 1, 20)},
+	)
+
+	want := map[string]float32{
+		"a": float32(1) / 61,
+		"b": float32(1)/62 + float32(1)/61,
+	}
+	for _, r := range res {
+		if r.Score != want[r.Url] {
+			t.Errorf("score of %s = %v, want %v", r.Url, r.Score, want[r.Url])
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"log"
+	"time"
+)
+
+type Stage string
+
+const (
+	StageDownloading  Stage = "downloading"
+	StageExtracting   Stage = "extracting"
+	StageDescribing   Stage = "describing"
+	StageTranscribing Stage = "transcribing"
+	StageStoring      Stage = "storing"
+	StageReindexing   Stage = "reindexing"
+	StageSkipped      Stage = "skipped"
+	StageDone         Stage = "done"
+	StageFailed       Stage = "failed"
+)
+
+// Event reports the progress of a Process or Reindex call. FramesDone and
+// FramesTotal accumulate across every video processed by the call.
+type Event struct {
+	Stage       Stage
+	Video       string
+	FramesDone  int
+	FramesTotal int
+	ETA         time.Duration
+	Err         error
+}
+
+type EventFunc func(Event)
+
+type tracker struct {
+	emit    EventFunc
+	stage   Stage
+	video   string
+	done    int
+	total   int
+	started time.Time
+}
+
+// send emits the current state. Without a listener, errors are logged so
+// they are not lost.
+func (t *tracker) send(err error) {
+	if t.emit == nil {
+		if err != nil {
+			log.Print(err)
+		}
+		return
+	}
+
+	t.emit(Event{
+		Stage:       t.stage,
+		Video:       t.video,
+		FramesDone:  t.done,
+		FramesTotal: t.total,
+		ETA:         t.eta(),
+		Err:         err,
+	})
+}
+
+func (t *tracker) enter(stage Stage, video string) {
+	t.stage = stage
+	t.video = video
+	t.send(nil)
+}
+
+func (t *tracker) extracted(frames int) {
+	t.queued(frames)
+	t.enter(StageDescribing, t.video)
+}
+
+// queued adds frames or audio chunks to the total without leaving the
+// current stage.
+func (t *tracker) queued(n int) {
+	t.total += n
+	if t.started.IsZero() {
+		t.started = time.Now()
+	}
+}
+
+// processed counts a frame or audio chunk as done, whether or not it could be
+// stored.
+func (t *tracker) processed(err error) {
+	t.done++
+	t.send(err)
+}
+
+// reindexed records the progress reported by a store while reindexing.
+func (t *tracker) reindexed(done, total int) {
+	if t.started.IsZero() {
+		t.started = time.Now()
+	}
+
+	t.stage = StageReindexing
+	t.done = done
+	t.total = total
+	t.send(nil)
+}
+
+func (t *tracker) fail(err error) {
+	t.send(err)
+}
+
+func (t *tracker) finish(err error) {
+	if err != nil {
+		t.stage = StageFailed
+	} else {
+		t.stage = StageDone
+	}
+
+	t.send(err)
+}
+
+// eta extrapolates the remaining time from the average time per frame so far.
+func (t *tracker) eta() time.Duration {
+	if t.done == 0 || t.started.IsZero() {
+		return 0
+	}
+
+	perFrame := time.Since(t.started) / time.Duration(t.done)
+
+	return perFrame * time.Duration(t.total-t.done)
+}
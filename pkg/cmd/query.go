@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/llm"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/store"
+)
+
+const (
+	// ModeHybrid fuses the rankings of the vector and keyword searches, so
+	// results are scored by reciprocal rank fusion rather than similarity.
+	ModeHybrid = "hybrid"
+	// ModeDense only ranks by embedding similarity. It is the default.
+	ModeDense = "dense"
+	// ModeKeyword only ranks by the words of the query, which suits proper
+	// nouns and on-screen text.
+	ModeKeyword = "keyword"
+)
+
+var SearchModes = []string{ModeHybrid, ModeDense, ModeKeyword}
+
+const (
+	// RewriteRaw embeds the query as it is, which is fastest and never
+	// drifts from what was asked.
+	RewriteRaw = "raw"
+	// RewriteHyDE embeds a hypothetical frame description written for the
+	// query, which embeds closer to the stored descriptions.
+	RewriteHyDE = "hyde"
+	// RewriteMulti searches with several paraphrased descriptions and fuses
+	// the rankings, which helps with vague queries.
+	RewriteMulti = "multi"
+)
+
+var RewriteModes = []string{RewriteRaw, RewriteHyDE, RewriteMulti}
+
+var listMarker = regexp.MustCompile(`^\s*(?:\d+[.)]|[-*•])\s+`)
+
+// QueryOptions tune a single search.
+type QueryOptions struct {
+	Limit int `json:"limit,omitempty"`
+	// Mode is one of SearchModes.
+	Mode string `json:"mode,omitempty"`
+	// Rewrite is one of RewriteModes, and Paraphrases the number of
+	// descriptions written by RewriteMulti.
+	Rewrite     string `json:"rewrite,omitempty"`
+	Paraphrases int    `json:"paraphrases,omitempty"`
+	// ClipGap is the largest gap in seconds between matching frames of a
+	// video that are still merged into one clip.
+	ClipGap float64 `json:"clip_gap"`
+	// ClipsPerVideo caps the clips of one video while other videos still
+	// have matches. Zero disables the cap.
+	ClipsPerVideo int `json:"clips_per_video"`
+	// Filter restricts the frames searched.
+	Filter store.Filter `json:"filter"`
+}
+
+// QueryOptions returns the configured search defaults.
+func (c *Command) QueryOptions() QueryOptions {
+	return QueryOptions{
+		Limit:         c.cfg.QueryLimit,
+		Mode:          c.cfg.SearchMode,
+		Rewrite:       c.cfg.QueryRewrite,
+		Paraphrases:   c.cfg.QueryParaphrases,
+		ClipGap:       c.cfg.ClipGap,
+		ClipsPerVideo: c.cfg.ClipsPerVideo,
+	}
+}
+
+// QueryResult holds the clips found for a query and the rewrites of the query
+// that were searched with, which help explain unexpected results. It is the
+// response body of the search API.
+type QueryResult struct {
+	Clips    []Clip   `json:"clips"`
+	Rewrites []string `json:"rewrites"`
+}
+
+// Query searches for frames matching query and merges adjacent matches into
+// clips. Depending on the mode, the rankings of the description vector for
+// every rewrite of the query, the image vector when an image embedding model
+// is set, and the keyword index are fused.
+func (c *Command) Query(ctx context.Context, query string, opts QueryOptions) (*QueryResult, error) {
+	if opts.Limit < 1 {
+		return nil, fmt.Errorf("limit must be positive, got %d", opts.Limit)
+	}
+
+	mode := cmp.Or(opts.Mode, ModeDense)
+	if !slices.Contains(SearchModes, mode) {
+		return nil, fmt.Errorf("unknown search mode %q", mode)
+	}
+
+	rewrite := cmp.Or(opts.Rewrite, RewriteHyDE)
+	if !slices.Contains(RewriteModes, rewrite) {
+		return nil, fmt.Errorf("unknown rewrite mode %q", rewrite)
+	}
+
+	candidates := uint64(opts.Limit * clipCandidates)
+
+	var (
+		res   QueryResult
+		lists [][]store.SearchResult
+	)
+	if mode != ModeKeyword {
+		texts, err := c.rewrite(ctx, query, rewrite, opts.Paraphrases)
+		if err != nil {
+			return nil, err
+		}
+		if rewrite != RewriteRaw {
+			res.Rewrites = texts
+		}
+
+		dense, err := c.searchDense(ctx, query, texts, candidates, opts.Filter)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, dense...)
+	}
+
+	if mode != ModeDense {
+		pts, err := c.db.SearchKeywords(ctx, query, candidates, opts.Filter)
+		// hybrid search still works on collections without a keyword index
+		if err != nil && !(mode == ModeHybrid && errors.Is(err, store.ErrNoKeywordIndex)) {
+			return nil, fmt.Errorf("keyword search failed: %w", err)
+		}
+		lists = append(lists, pts)
+	}
+
+	pts := fuseRanks(lists...)
+	if len(pts) == 0 {
+		return nil, fmt.Errorf("no results found")
+	}
+
+	res.Clips = diversify(groupClips(pts, opts.ClipGap), opts.Limit, opts.ClipsPerVideo)
+
+	return &res, nil
+}
+
+// rewrite returns the texts whose embeddings stand for query.
+func (c *Command) rewrite(ctx context.Context, query, mode string, paraphrases int) ([]string, error) {
+	switch mode {
+	case RewriteRaw:
+		return []string{query}, nil
+	case RewriteMulti:
+		return c.paraphrase(ctx, query, paraphrases)
+	}
+
+	prompt, err := c.prompts.Query.Render(llm.PromptData{Query: query})
+	if err != nil {
+		return nil, err
+	}
+
+	desc, err := c.models.Text.Generate(ctx, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get description: %w", err)
+	}
+
+	return []string{desc}, nil
+}
+
+// paraphrase asks for n frame descriptions matching query, one per line.
+func (c *Command) paraphrase(ctx context.Context, query string, n int) ([]string, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("paraphrases must be positive, got %d", n)
+	}
+
+	prompt, err := c.prompts.Paraphrase.Render(llm.PromptData{Query: query, Count: n})
+	if err != nil {
+		return nil, err
+	}
+
+	answer, err := c.models.Text.Generate(ctx, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get paraphrases: %w", err)
+	}
+
+	var res []string
+	for _, line := range strings.Split(answer, "\n") {
+		// models tend to number or bullet the lines despite being asked not to
+		line = strings.TrimSpace(listMarker.ReplaceAllString(line, ""))
+		if line != "" && len(res) < n {
+			res = append(res, line)
+		}
+	}
+
+	if len(res) == 0 {
+		return nil, fmt.Errorf("no paraphrases in answer %q", answer)
+	}
+
+	return res, nil
+}
+
+// searchDense returns the rankings of the description vector for every text
+// and, with an image embedding model, of the image vector for the raw query.
+func (c *Command) searchDense(ctx context.Context, query string, texts []string, limit uint64, filter store.Filter) ([][]store.SearchResult, error) {
+	var lists [][]store.SearchResult
+	for _, text := range texts {
+		embedding, err := c.models.Embedding.Embed(ctx, text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get embedding: %w", err)
+		}
+
+		pts, err := c.db.Search(ctx, store.VectorDescription, embedding, limit, filter)
+		if err != nil {
+			return nil, fmt.Errorf("search failed: %w", err)
+		}
+		lists = append(lists, pts)
+	}
+
+	if c.models.Image == nil {
+		return lists, nil
+	}
+
+	embedding, err := c.models.Image.Embed(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image embedding: %w", err)
+	}
+
+	images, err := c.db.Search(ctx, store.VectorImage, embedding, limit, filter)
+	if errors.Is(err, store.ErrNoImageIndex) {
+		// descriptions are still searched until the store is reindexed
+		return lists, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("image search failed: %w", err)
+	}
+
+	return append(lists, images), nil
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/filestore"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/llm"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/qdrant"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/store"
+)
+
+// OpenStore connects to the vector store behind cfg.DatabaseURL: a file://
+// URL selects the embedded store, anything else a Qdrant server. The vector
+// dimensions are probed from the embedding models, so switching models never
+// mixes incompatible embeddings.
+func OpenStore(ctx context.Context, cfg *config.Config) (store.VectorStore, error) {
+	models, err := llm.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	probe, err := models.Embedding.Embed(ctx, "dimension probe")
+	if err != nil {
+		return nil, fmt.Errorf("failed to probe embedding model %s: %w", cfg.EmbeddingModel, err)
+	}
+
+	dims := store.Dimensions{Description: len(probe)}
+
+	if models.Image != nil {
+		probe, err := models.Image.Embed(ctx, "dimension probe")
+		if err != nil {
+			return nil, fmt.Errorf("failed to probe image embedding model %s: %w", cfg.ImageEmbeddingModel, err)
+		}
+		dims.Image = len(probe)
+	}
+
+	return openStore(cfg, dims)
+}
+
+// OpenStoreUnprobed connects to the vector store like OpenStore without
+// reaching the embedding models, for commands that only list, filter, delete
+// or clean frames. The store cannot embed or search until it is opened with
+// OpenStore.
+func OpenStoreUnprobed(cfg *config.Config) (store.VectorStore, error) {
+	return openStore(cfg, store.Dimensions{})
+}
+
+func openStore(cfg *config.Config, dims store.Dimensions) (store.VectorStore, error) {
+	u, err := url.Parse(cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL format: %w", err)
+	}
+
+	if u.Scheme == "file" {
+		return filestore.New(u.Path, cfg.EmbeddingModel, dims)
+	}
+
+	return qdrant.New(cfg.DatabaseURL, cfg.EmbeddingModel, dims)
+}
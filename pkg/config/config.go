@@ -1,13 +1,96 @@
 package config
 
 type Config struct {
-	SamplingInterval int
-	SamplingModel    string
-	EmbeddingModel   string
-	QueryLimit       int
-	QueryModel       string
-	OllamaURL        string
-	DatabaseURL      string
-	ServerPort       uint
-	Debug            bool
+	SamplingMode        string
+	SamplingInterval    int
+	SceneThreshold      float64
+	MinGap              float64
+	MaxGap              float64
+	DedupThreshold      int
+	Force               bool
+	SamplingModel       string
+	Workers             int
+	RateLimit           float64
+	Extensions          []string
+	Subtitles           bool
+	SubtitleLangs       string
+	SubtitleWindow      float64
+	Fusion              bool
+	FramePrompt         string
+	Structured          bool
+	TranscriptionURL    string
+	TranscriptionModel  string
+	TranscriptionChunk  float64
+	Channel             string
+	Tags                []string
+	EmbeddingModel      string
+	QueryLimit          int
+	SearchMode          string
+	ClipGap             float64
+	ClipsPerVideo       int
+	QueryModel          string
+	QueryPrompt         string
+	QueryRewrite        string
+	QueryParaphrases    int
+	ParaphrasePrompt    string
+	VisionBackend       string
+	TextBackend         string
+	EmbeddingBackend    string
+	OllamaURL           string
+	OpenAIURL           string
+	OpenAIKey           string
+	ImageEmbeddingURL   string
+	ImageEmbeddingModel string
+	DatabaseURL         string
+	WorkDir             string
+	S3Endpoint          string
+	S3Region            string
+	S3AccessKey         string
+	S3SecretKey         string
+	ServerPort          uint
+	JobWorkers          int
+	JobsFile            string
+	Debug               bool
+}
+
+// Overrides holds settings that replace the configured defaults for a
+// single processing run. They can be set by API clients, so settings naming
+// files on the server, such as the frame prompt, are left out.
+type Overrides struct {
+	DedupThreshold *int     `json:"dedup_threshold,omitempty"`
+	Force          *bool    `json:"force,omitempty"`
+	Subtitles      *bool    `json:"subtitles,omitempty"`
+	Fusion         *bool    `json:"fusion,omitempty"`
+	Structured     *bool    `json:"structured,omitempty"`
+	Channel        *string  `json:"channel,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+}
+
+// Apply returns a copy of cfg with the overrides applied.
+func (o Overrides) Apply(cfg *Config) *Config {
+	res := *cfg
+
+	if o.DedupThreshold != nil {
+		res.DedupThreshold = *o.DedupThreshold
+	}
+	if o.Force != nil {
+		res.Force = *o.Force
+	}
+	if o.Subtitles != nil {
+		res.Subtitles = *o.Subtitles
+	}
+	if o.Fusion != nil {
+		res.Fusion = *o.Fusion
+	}
+	if o.Structured != nil {
+		res.Structured = *o.Structured
+	}
+	if o.Channel != nil {
+		res.Channel = *o.Channel
+	}
+	if o.Tags != nil {
+		res.Tags = o.Tags
+	}
+
+	return &res
 }
@@ -0,0 +1,770 @@
+package filestore
+
+import (
+	"cmp"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/store"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/video"
+)
+
+// errLocked is returned when another process has the database open.
+var errLocked = errors.New("database is in use by another process")
+
+// Store is an embedded vector store kept in memory and persisted to a single
+// local file. Searches are brute force, which is fast enough for personal
+// libraries of a few hundred thousand frames. Frames of every embedding model
+// share the file, but only those of the configured model are searched, listed
+// and deleted by video; Cleanup removes them all. The store holds a lock file
+// next to the database until it is closed, so no other process overwrites its
+// changes.
+type Store struct {
+	mu        sync.RWMutex
+	path      string
+	lock      *os.File
+	model     string
+	dimension int
+	// imageDimension is the size of image vectors, or zero when image
+	// embeddings are disabled.
+	imageDimension int
+	points         map[string]*point
+	// catalog holds the catalog record of each video, keyed by video ID.
+	catalog map[string]*video.Metadata
+	// stale is set when stored frames of the model have a different
+	// dimension; reads and writes fail with it until the store is cleaned.
+	stale error
+}
+
+var _ store.VectorStore = (*Store)(nil)
+
+// stagingBatchSize is the number of points re-embedded between writes of the
+// staging file.
+const stagingBatchSize = 256
+
+type point struct {
+	Kind           string
+	Vector         []float32
+	ImageVector    []float32
+	URL            string
+	VideoID        string
+	SamplingModel  string
+	EmbeddingModel string
+	Channel        string
+	Tags           []string
+	PromptTemplate string
+	PromptHash     string
+	FramesTotal    int
+	FramesStored   int
+	IndexedAt      time.Time
+	Timestamp      float64
+	EndTimestamp   float64
+	Description    string
+	Analysis       *video.Analysis
+}
+
+func New(path string, embeddingModel string, dims store.Dimensions) (*Store, error) {
+	dimension := dims.Description
+	s := &Store{
+		path:      path,
+		model:     embeddingModel,
+		dimension: dimension,
+		points:    map[string]*point{},
+		catalog:   map[string]*video.Metadata{},
+
+		imageDimension: dims.Image,
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database dir: %w", err)
+	}
+
+	lock, err := lockFile(path + ".lock")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database %s: %w", path, err)
+	}
+	s.lock = lock
+
+	if err := s.load(); err != nil {
+		unlockFile(lock)
+		return nil, err
+	}
+
+	for _, pt := range s.points {
+		if dimension > 0 && pt.EmbeddingModel == embeddingModel && len(pt.Vector) != dimension {
+			s.stale = fmt.Errorf(
+				"database holds %d-dimensional embeddings for model %q but it now produces %d; run reindex --from-model %s to rebuild it",
+				len(pt.Vector), embeddingModel, dimension, embeddingModel,
+			)
+			break
+		}
+	}
+
+	return s, nil
+}
+
+// load reads the points and the catalog from the database file, if there is
+// one yet.
+func (s *Store) load() error {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer f.Close()
+
+	dec := gob.NewDecoder(f)
+	if err := dec.Decode(&s.points); err != nil {
+		return fmt.Errorf("failed to decode database: %w", err)
+	}
+	if err := dec.Decode(&s.catalog); err != nil {
+		return fmt.Errorf("failed to decode catalog: %w", err)
+	}
+
+	return nil
+}
+
+// Close releases the lock on the database. Every change is saved as it is
+// made, so there is nothing left to write.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return unlockFile(s.lock)
+}
+
+func (s *Store) Store(ctx context.Context, src store.Source, frames []*video.Frame) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stale != nil {
+		return s.stale
+	}
+
+	if src.Metadata != nil {
+		s.catalog[src.VideoID] = src.Metadata
+	}
+
+	for _, frame := range frames {
+		if frame.Kind == "" {
+			return fmt.Errorf("frame at %s has no kind", frame.Timestamp)
+		}
+
+		s.points[store.PointID(src, frame).String()] = &point{
+			Kind:           frame.Kind,
+			Vector:         frame.Embedding,
+			ImageVector:    frame.ImageEmbedding,
+			URL:            src.URL,
+			VideoID:        src.VideoID,
+			SamplingModel:  src.SamplingModel,
+			EmbeddingModel: src.EmbeddingModel,
+			Channel:        src.Channel,
+			Tags:           src.Tags,
+			PromptTemplate: src.PromptTemplate,
+			PromptHash:     src.PromptHash,
+			FramesTotal:    src.FramesTotal,
+			FramesStored:   src.FramesStored,
+			IndexedAt:      src.IndexedAt.UTC(),
+			Timestamp:      frame.Timestamp.Seconds(),
+			EndTimestamp:   frame.End.Seconds(),
+			Description:    frame.Description,
+			Analysis:       frame.Analysis,
+		}
+	}
+
+	return s.save()
+}
+
+func (s *Store) Search(ctx context.Context, vector string, embedding []float32, limit uint64, filter store.Filter) ([]store.SearchResult, error) {
+	dimension := s.dimension
+	if vector == store.VectorImage {
+		if s.imageDimension == 0 {
+			return nil, fmt.Errorf("image embeddings are not enabled")
+		}
+		dimension = s.imageDimension
+	}
+	if len(embedding) != dimension {
+		return nil, fmt.Errorf("embedding dimensions must be %d, got %d", dimension, len(embedding))
+	}
+	if limit < 1 {
+		return nil, fmt.Errorf("limit must be positive, got %d", limit)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.stale != nil {
+		return nil, s.stale
+	}
+
+	res := make([]store.SearchResult, 0, len(s.points))
+	for _, pt := range s.points {
+		v := pt.vector(vector)
+		if pt.EmbeddingModel != s.model || len(v) != len(embedding) || !pt.matchesFilter(filter) {
+			continue
+		}
+
+		score := cosine(embedding, v)
+		if score < filter.MinScore {
+			continue
+		}
+
+		res = append(res, pt.result(score, s.catalog[pt.VideoID]))
+	}
+
+	return topResults(res, limit), nil
+}
+
+// SearchKeywords scores the descriptions of the model's frames with BM25,
+// computing term weights and document frequencies on every search.
+func (s *Store) SearchKeywords(ctx context.Context, query string, limit uint64, filter store.Filter) ([]store.SearchResult, error) {
+	if limit < 1 {
+		return nil, fmt.Errorf("limit must be positive, got %d", limit)
+	}
+
+	terms := store.QueryTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.stale != nil {
+		return nil, s.stale
+	}
+
+	type candidate struct {
+		pt      *point
+		weights map[uint32]float32
+	}
+
+	var (
+		candidates []candidate
+		docs       int
+		df         = map[uint32]int{}
+	)
+	for _, pt := range s.points {
+		if pt.EmbeddingModel != s.model {
+			continue
+		}
+
+		weights := store.TermWeights(pt.Description)
+		docs++
+		for _, id := range terms {
+			if _, ok := weights[id]; ok {
+				df[id]++
+			}
+		}
+
+		if pt.matchesFilter(filter) {
+			candidates = append(candidates, candidate{pt, weights})
+		}
+	}
+
+	var res []store.SearchResult
+	for _, c := range candidates {
+		var score float64
+		for _, id := range terms {
+			if w, ok := c.weights[id]; ok {
+				n := float64(df[id])
+				score += float64(w) * math.Log(1+(float64(docs)-n+0.5)/(n+0.5))
+			}
+		}
+
+		if score > 0 {
+			res = append(res, c.pt.result(float32(score), s.catalog[c.pt.VideoID]))
+		}
+	}
+
+	return topResults(res, limit), nil
+}
+
+func (s *Store) Facets(ctx context.Context, filter store.Filter, limit int) (*store.Facets, error) {
+	if limit < 1 {
+		return nil, fmt.Errorf("limit must be positive, got %d", limit)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.stale != nil {
+		return nil, s.stale
+	}
+
+	objects, scenes, colors := map[string]uint64{}, map[string]uint64{}, map[string]uint64{}
+	for _, pt := range s.points {
+		if pt.EmbeddingModel != s.model || pt.Analysis == nil || !pt.matchesFilter(filter) {
+			continue
+		}
+
+		for _, o := range pt.Analysis.Objects {
+			objects[o]++
+		}
+		scenes[pt.Analysis.SceneType]++
+		for _, c := range pt.Analysis.Colors {
+			colors[c]++
+		}
+	}
+
+	return &store.Facets{
+		Objects:    facetCounts(objects, limit),
+		SceneTypes: facetCounts(scenes, limit),
+		Colors:     facetCounts(colors, limit),
+	}, nil
+}
+
+func (s *Store) IsIndexed(ctx context.Context, field, value, samplingModel, embeddingModel string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.stale != nil {
+		return false, s.stale
+	}
+
+	count, total := 0, 0
+	for _, pt := range s.points {
+		if pt.Kind != video.KindSpeech && pt.field(field) == value && pt.SamplingModel == samplingModel && pt.EmbeddingModel == embeddingModel {
+			count++
+			total = cmp.Or(pt.FramesStored, pt.FramesTotal)
+		}
+	}
+
+	return count > 0 && count >= total, nil
+}
+
+func (s *Store) MarkIndexed(ctx context.Context, src store.Source, frames int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stale != nil {
+		return s.stale
+	}
+
+	for _, pt := range s.points {
+		if pt.Kind != video.KindSpeech && pt.VideoID == src.VideoID && pt.SamplingModel == src.SamplingModel && pt.EmbeddingModel == src.EmbeddingModel {
+			pt.FramesStored = frames
+		}
+	}
+
+	return s.save()
+}
+
+func (s *Store) DeleteFrames(ctx context.Context, videoID, samplingModel, embeddingModel string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteFunc(func(pt *point) bool {
+		return pt.VideoID == videoID && pt.SamplingModel == samplingModel && pt.EmbeddingModel == embeddingModel
+	})
+
+	return s.save()
+}
+
+func (s *Store) Relocate(ctx context.Context, videoID, url string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	moved := false
+	for _, pt := range s.points {
+		if pt.EmbeddingModel == s.model && pt.VideoID == videoID && pt.URL != url {
+			pt.URL = url
+			moved = true
+		}
+	}
+
+	if !moved {
+		return nil
+	}
+
+	return s.save()
+}
+
+func (s *Store) List(ctx context.Context) ([]store.VideoSummary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.videos(func(pt *point) bool { return pt.EmbeddingModel == s.model }), nil
+}
+
+func (s *Store) Get(ctx context.Context, idOrURL string) (*store.VideoSummary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := s.videos(func(pt *point) bool { return pt.EmbeddingModel == s.model && pt.matches(idOrURL) })
+	if len(res) == 0 {
+		return nil, fmt.Errorf("%w: %s", store.ErrVideoNotFound, idOrURL)
+	}
+
+	return &res[0], nil
+}
+
+func (s *Store) Delete(ctx context.Context, idOrURL string) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := s.deleteFunc(func(pt *point) bool { return pt.EmbeddingModel == s.model && pt.matches(idOrURL) })
+	if count == 0 {
+		return 0, fmt.Errorf("%w: %s", store.ErrVideoNotFound, idOrURL)
+	}
+
+	return count, s.save()
+}
+
+func (s *Store) Cleanup(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.points = map[string]*point{}
+	s.catalog = map[string]*video.Metadata{}
+	s.stale = nil
+
+	if err := os.Remove(s.stagingPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove staged frames: %w", err)
+	}
+
+	return s.save()
+}
+
+// Reindex re-embeds the points of fromModel into a staging file next to the
+// database, which is merged in once every point is done. Points already in
+// the staging file or already embedded with the store's model are skipped, so
+// an interrupted run resumes where it left off.
+func (s *Store) Reindex(ctx context.Context, fromModel string, embed store.EmbedFunc, progress func(done, total int)) error {
+	staged, err := s.loadStaged()
+	if err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	var sources []*point
+	for _, pt := range s.points {
+		if pt.EmbeddingModel == fromModel {
+			copied := *pt
+			sources = append(sources, &copied)
+		}
+	}
+	s.mu.RUnlock()
+
+	if len(sources) == 0 {
+		return fmt.Errorf("no frames found for embedding model %q", fromModel)
+	}
+
+	done := 0
+	progress(done, len(sources))
+
+	for batch := range slices.Chunk(sources, stagingBatchSize) {
+		for _, src := range batch {
+			id := store.PointID(src.source(s.model), src.frame()).String()
+			if _, ok := staged[id]; ok || src.Description == "" || s.embedded(id) {
+				continue
+			}
+
+			embedding, err := embed(ctx, src.Description)
+			if err != nil {
+				return fmt.Errorf("failed to get embedding: %w", err)
+			} else if len(embedding) != s.dimension {
+				return fmt.Errorf("embedding dimensions must be %d, got %d", s.dimension, len(embedding))
+			}
+
+			pt := *src
+			pt.Vector = embedding
+			pt.EmbeddingModel = s.model
+			staged[id] = &pt
+		}
+
+		if err := writeGob(s.stagingPath(), staged); err != nil {
+			return err
+		}
+
+		done += len(batch)
+		progress(done, len(sources))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	maps.Copy(s.points, staged)
+	s.stale = nil
+
+	if err := s.save(); err != nil {
+		return err
+	}
+
+	return os.Remove(s.stagingPath())
+}
+
+// embedded reports whether the point with id already has an embedding of the
+// store's dimension, from an earlier reindex or from processing.
+func (s *Store) embedded(id string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pt, ok := s.points[id]
+
+	return ok && len(pt.Vector) == s.dimension
+}
+
+func (s *Store) stagingPath() string {
+	return s.path + ".reindex"
+}
+
+func (s *Store) loadStaged() (map[string]*point, error) {
+	staged := map[string]*point{}
+
+	f, err := os.Open(s.stagingPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return staged, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to open staged frames: %w", err)
+	}
+	defer f.Close()
+
+	if err := gob.NewDecoder(f).Decode(&staged); err != nil {
+		return nil, fmt.Errorf("failed to decode staged frames: %w", err)
+	}
+
+	// frames staged for another model are of no use to this run
+	maps.DeleteFunc(staged, func(id string, pt *point) bool {
+		return pt.EmbeddingModel != s.model || len(pt.Vector) != s.dimension
+	})
+
+	return staged, nil
+}
+
+func (s *Store) videos(keep func(pt *point) bool) []store.VideoSummary {
+	summaries := map[string]*store.VideoSummary{}
+	for _, pt := range s.points {
+		if !keep(pt) {
+			continue
+		}
+
+		summary, ok := summaries[pt.VideoID]
+		if !ok {
+			summary = &store.VideoSummary{ID: pt.VideoID}
+			summaries[pt.VideoID] = summary
+		}
+
+		summary.Frames++
+		summary.URLs = appendUnique(summary.URLs, pt.URL)
+		summary.SamplingModels = appendUnique(summary.SamplingModels, pt.SamplingModel)
+		summary.EmbeddingModels = appendUnique(summary.EmbeddingModels, pt.EmbeddingModel)
+		if pt.IndexedAt.After(summary.IndexedAt) {
+			summary.IndexedAt = pt.IndexedAt
+		}
+	}
+
+	res := make([]store.VideoSummary, 0, len(summaries))
+	for id, summary := range summaries {
+		summary.Metadata = s.catalog[id]
+		res = append(res, *summary)
+	}
+
+	slices.SortFunc(res, func(a, b store.VideoSummary) int {
+		return b.IndexedAt.Compare(a.IndexedAt)
+	})
+
+	return res
+}
+
+// deleteFunc removes the matching points, and the catalog records of videos
+// left without any, and returns how many points there were. The caller must
+// hold the write lock.
+func (s *Store) deleteFunc(del func(pt *point) bool) uint64 {
+	var count uint64
+	for id, pt := range s.points {
+		if del(pt) {
+			delete(s.points, id)
+			count++
+		}
+	}
+
+	if count > 0 {
+		kept := map[string]bool{}
+		for _, pt := range s.points {
+			kept[pt.VideoID] = true
+		}
+
+		maps.DeleteFunc(s.catalog, func(id string, _ *video.Metadata) bool { return !kept[id] })
+	}
+
+	return count
+}
+
+// save atomically replaces the database file, writing the catalog after the
+// points. The caller must hold the lock.
+func (s *Store) save() error {
+	return writeGob(s.path, s.points, s.catalog)
+}
+
+// writeGob atomically replaces the file at path with the encoded values.
+func writeGob(path string, values ...any) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create database dir: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create database: %w", err)
+	}
+
+	enc := gob.NewEncoder(f)
+	for _, v := range values {
+		if err := enc.Encode(v); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to encode database: %w", err)
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write database: %w", err)
+	}
+
+	return os.Rename(tmp, path)
+}
+
+func (pt *point) source(embeddingModel string) store.Source {
+	return store.Source{
+		VideoID:        pt.VideoID,
+		URL:            pt.URL,
+		SamplingModel:  pt.SamplingModel,
+		EmbeddingModel: embeddingModel,
+		Channel:        pt.Channel,
+		Tags:           pt.Tags,
+		PromptTemplate: pt.PromptTemplate,
+		PromptHash:     pt.PromptHash,
+		FramesTotal:    pt.FramesTotal,
+		FramesStored:   pt.FramesStored,
+		IndexedAt:      pt.IndexedAt,
+	}
+}
+
+func (pt *point) frame() *video.Frame {
+	return &video.Frame{
+		Kind:        pt.Kind,
+		Timestamp:   store.Seconds(pt.Timestamp),
+		End:         store.Seconds(pt.EndTimestamp),
+		Description: pt.Description,
+		Analysis:    pt.Analysis,
+	}
+}
+
+func (pt *point) result(score float32, meta *video.Metadata) store.SearchResult {
+	return store.SearchResult{
+		Kind:         pt.Kind,
+		Url:          pt.URL,
+		Timestamp:    pt.Timestamp,
+		EndTimestamp: pt.EndTimestamp,
+		Description:  pt.Description,
+		Score:        score,
+		Metadata:     meta,
+		Analysis:     pt.Analysis,
+	}
+}
+
+// vector returns the named vector of the point, which is nil for image
+// vectors of frames processed without an image embedding model.
+func (pt *point) vector(name string) []float32 {
+	if name == store.VectorImage {
+		return pt.ImageVector
+	}
+
+	return pt.Vector
+}
+
+func (pt *point) field(name string) string {
+	switch name {
+	case "url":
+		return pt.URL
+	case "video_id":
+		return pt.VideoID
+	default:
+		return ""
+	}
+}
+
+func (pt *point) matches(idOrURL string) bool {
+	return pt.VideoID == idOrURL || pt.URL == idOrURL
+}
+
+func (pt *point) matchesFilter(filter store.Filter) bool {
+	switch {
+	case !filter.MatchesKind(pt.Kind):
+		return false
+	case len(filter.Videos) > 0 && !slices.ContainsFunc(filter.Videos, pt.matches):
+		return false
+	case filter.From > 0 && pt.EndTimestamp < filter.From:
+		return false
+	case filter.To > 0 && pt.Timestamp > filter.To:
+		return false
+	case !filter.IndexedAfter.IsZero() && pt.IndexedAt.Before(filter.IndexedAfter):
+		return false
+	case !filter.IndexedBefore.IsZero() && pt.IndexedAt.After(filter.IndexedBefore):
+		return false
+	case len(filter.Channels) > 0 && !slices.Contains(filter.Channels, pt.Channel):
+		return false
+	case len(filter.Tags) > 0 && !slices.ContainsFunc(filter.Tags, func(tag string) bool { return slices.Contains(pt.Tags, tag) }):
+		return false
+	case !filter.MatchesAnalysis(pt.Analysis):
+		return false
+	}
+
+	return true
+}
+
+// topResults returns the limit best scoring results.
+func topResults(res []store.SearchResult, limit uint64) []store.SearchResult {
+	slices.SortFunc(res, func(a, b store.SearchResult) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+
+	return res[:min(len(res), int(limit))]
+}
+
+// facetCounts returns the limit most common values, ties broken by value.
+func facetCounts(counts map[string]uint64, limit int) []store.FacetCount {
+	res := make([]store.FacetCount, 0, len(counts))
+	for value, count := range counts {
+		res = append(res, store.FacetCount{Value: value, Count: count})
+	}
+
+	slices.SortFunc(res, func(a, b store.FacetCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Value, b.Value))
+	})
+
+	return res[:min(len(res), limit)]
+}
+
+func cosine(a, b []float32) float32 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+
+	if na == 0 || nb == 0 {
+		return 0
+	}
+
+	return float32(dot / (math.Sqrt(na) * math.Sqrt(nb)))
+}
+
+func appendUnique(values []string, value string) []string {
+	if value == "" || slices.Contains(values, value) {
+		return values
+	}
+
+	return append(values, value)
+}
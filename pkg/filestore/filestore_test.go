@@ -0,0 +1,510 @@
+package filestore
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/store"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/video"
+)
+
+func testSource(videoID, model string) store.Source {
+	return store.Source{
+		VideoID:        videoID,
+		URL:            "https://example.com/" + videoID,
+		SamplingModel:  "llava",
+		EmbeddingModel: model,
+		Channel:        "channel-" + videoID,
+		FramesTotal:    2,
+		IndexedAt:      time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+func testFrame(seconds float64, description string, embedding ...float32) *video.Frame {
+	return &video.Frame{
+		Kind:        video.KindVisual,
+		Timestamp:   store.Seconds(seconds),
+		End:         store.Seconds(seconds),
+		Description: description,
+		Embedding:   embedding,
+	}
+}
+
+// testStore opens a store in a temporary directory holding two frames of
+// video a and one of video b.
+func testStore(t *testing.T) *Store {
+	t.Helper()
+
+	s, err := New(filepath.Join(t.TempDir(), "frames.db"), "nomic", store.Dimensions{Description: 2})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	ctx := context.Background()
+	if err := s.Store(ctx, testSource("a", "nomic"), []*video.Frame{
+		testFrame(1, "a red car", 1, 0),
+		testFrame(2, "a blue boat", 0, 1),
+	}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if err := s.Store(ctx, testSource("b", "nomic"), []*video.Frame{
+		testFrame(1, "a red bike", 1, 1),
+	}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	return s
+}
+
+// reopen closes s and opens its database again with the given model.
+func reopen(t *testing.T, s *Store, embeddingModel string, dims store.Dimensions) *Store {
+	t.Helper()
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, err := New(s.path, embeddingModel, dims)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { reopened.Close() })
+
+	return reopened
+}
+
+func descriptions(res []store.SearchResult) []string {
+	var out []string
+	for _, r := range res {
+		out = append(out, r.Description)
+	}
+
+	return out
+}
+
+func TestStorePersists(t *testing.T) {
+	s := testStore(t)
+
+	reopened := reopen(t, s, "nomic", store.Dimensions{Description: 2})
+
+	if got, want := len(reopened.points), 3; got != want {
+		t.Fatalf("reopened store has %d points, want %d", got, want)
+	}
+	for _, pt := range reopened.points {
+		if pt.Channel != "channel-"+pt.VideoID {
+			t.Errorf("point of video %s has channel %q", pt.VideoID, pt.Channel)
+		}
+	}
+}
+
+func TestNewLocked(t *testing.T) {
+	s := testStore(t)
+
+	if _, err := New(s.path, "nomic", store.Dimensions{Description: 2}); !errors.Is(err, errLocked) {
+		t.Fatalf("New on an open database error = %v, want %v", err, errLocked)
+	}
+
+	reopen(t, s, "nomic", store.Dimensions{Description: 2})
+}
+
+func TestNewStale(t *testing.T) {
+	s := testStore(t)
+
+	reopened := reopen(t, s, "nomic", store.Dimensions{Description: 3})
+
+	if _, err := reopened.Search(context.Background(), store.VectorDescription, []float32{1, 0, 0}, 1, store.Filter{}); err == nil {
+		t.Error("Search succeeded on a store of a different dimension")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	s := testStore(t)
+
+	tests := []struct {
+		name      string
+		embedding []float32
+		limit     uint64
+		filter    store.Filter
+		want      []string
+		wantErr   bool
+	}{
+		{
+			name:      "ranks by similarity",
+			embedding: []float32{1, 0},
+			limit:     3,
+			want:      []string{"a red car", "a red bike", "a blue boat"},
+		},
+		{
+			name:      "limits results",
+			embedding: []float32{0, 1},
+			limit:     1,
+			want:      []string{"a blue boat"},
+		},
+		{
+			name:      "filters by video",
+			embedding: []float32{1, 0},
+			limit:     3,
+			filter:    store.Filter{Videos: []string{"b"}},
+			want:      []string{"a red bike"},
+		},
+		{
+			name:      "filters by channel",
+			embedding: []float32{1, 0},
+			limit:     3,
+			filter:    store.Filter{Channels: []string{"channel-a"}},
+			want:      []string{"a red car", "a blue boat"},
+		},
+		{
+			name:      "filters by time window",
+			embedding: []float32{1, 0},
+			limit:     3,
+			filter:    store.Filter{From: 1.5},
+			want:      []string{"a blue boat"},
+		},
+		{
+			name:      "filters by index date",
+			embedding: []float32{1, 0},
+			limit:     3,
+			filter:    store.Filter{IndexedAfter: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:      "drops low scores",
+			embedding: []float32{1, 0},
+			limit:     3,
+			filter:    store.Filter{MinScore: 0.5},
+			want:      []string{"a red car", "a red bike"},
+		},
+		{
+			name:      "wrong dimension",
+			embedding: []float32{1, 0, 0},
+			limit:     3,
+			wantErr:   true,
+		},
+		{
+			name:      "zero limit",
+			embedding: []float32{1, 0},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.Search(context.Background(), store.VectorDescription, tt.embedding, tt.limit, tt.filter)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Search succeeded, want an error")
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+
+			if got := descriptions(res); !slices.Equal(got, tt.want) {
+				t.Errorf("Search = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchImages(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "frames.db"), "nomic", store.Dimensions{Description: 2, Image: 2})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	red, blue := testFrame(1, "a red car", 1, 0), testFrame(2, "a blue boat", 1, 0)
+	red.ImageEmbedding, blue.ImageEmbedding = []float32{1, 0}, []float32{0, 1}
+	if err := s.Store(ctx, testSource("a", "nomic"), []*video.Frame{red, blue, testFrame(3, "no image", 1, 0)}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	res, err := s.Search(ctx, store.VectorImage, []float32{0, 1}, 3, store.Filter{})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if got, want := descriptions(res), []string{"a blue boat", "a red car"}; !slices.Equal(got, want) {
+		t.Errorf("Search = %q, want %q", got, want)
+	}
+
+	if _, err := testStore(t).Search(ctx, store.VectorImage, []float32{0, 1}, 3, store.Filter{}); err == nil {
+		t.Error("Search succeeded on a store without image embeddings")
+	}
+}
+
+func TestSearchKeywords(t *testing.T) {
+	s := testStore(t)
+
+	tests := []struct {
+		name   string
+		query  string
+		limit  uint64
+		filter store.Filter
+		want   []string
+	}{
+		{name: "ranks by matching terms", query: "red car", limit: 3, want: []string{"a red car", "a red bike"}},
+		{name: "case and punctuation", query: "BIKE!", limit: 3, want: []string{"a red bike"}},
+		{name: "limits results", query: "red car", limit: 1, want: []string{"a red car"}},
+		{name: "filters", query: "red", limit: 3, filter: store.Filter{Videos: []string{"b"}}, want: []string{"a red bike"}},
+		{name: "no match", query: "tree", limit: 3},
+		{name: "no terms", query: "?!", limit: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.SearchKeywords(context.Background(), tt.query, tt.limit, tt.filter)
+			if err != nil {
+				t.Fatalf("SearchKeywords: %v", err)
+			}
+
+			if got := descriptions(res); !slices.Equal(got, tt.want) {
+				t.Errorf("SearchKeywords = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsIndexed(t *testing.T) {
+	tests := []struct {
+		name     string
+		field    string
+		value    string
+		sampling string
+		marked   int
+		want     bool
+	}{
+		{name: "every frame stored", field: "video_id", value: "a", sampling: "llava", want: true},
+		{name: "by url", field: "url", value: "https://example.com/a", sampling: "llava", want: true},
+		{name: "frames missing", field: "video_id", value: "b", sampling: "llava", want: false},
+		{name: "frames missing but marked", field: "video_id", value: "b", sampling: "llava", marked: 1, want: true},
+		{name: "other sampling model", field: "video_id", value: "a", sampling: "moondream", want: false},
+		{name: "unknown video", field: "video_id", value: "c", sampling: "llava", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testStore(t)
+			ctx := context.Background()
+
+			if tt.marked > 0 {
+				if err := s.MarkIndexed(ctx, testSource(tt.value, "nomic"), tt.marked); err != nil {
+					t.Fatalf("MarkIndexed: %v", err)
+				}
+			}
+
+			got, err := s.IsIndexed(ctx, tt.field, tt.value, tt.sampling, "nomic")
+			if err != nil {
+				t.Fatalf("IsIndexed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsIndexed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteFrames(t *testing.T) {
+	tests := []struct {
+		name     string
+		videoID  string
+		sampling string
+		want     int
+	}{
+		{name: "deletes the video", videoID: "a", sampling: "llava", want: 1},
+		{name: "keeps other sampling models", videoID: "a", sampling: "moondream", want: 3},
+		{name: "unknown video", videoID: "c", sampling: "llava", want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testStore(t)
+
+			if err := s.DeleteFrames(context.Background(), tt.videoID, tt.sampling, "nomic"); err != nil {
+				t.Fatalf("DeleteFrames: %v", err)
+			}
+			if got := len(s.points); got != tt.want {
+				t.Errorf("store has %d points, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		name    string
+		idOrURL string
+		want    uint64
+		wantErr error
+	}{
+		{name: "by id", idOrURL: "a", want: 2},
+		{name: "by url", idOrURL: "https://example.com/b", want: 1},
+		{name: "unknown video", idOrURL: "c", wantErr: store.ErrVideoNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testStore(t)
+			ctx := context.Background()
+
+			got, err := s.Delete(ctx, tt.idOrURL)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Delete error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Delete = %d, want %d", got, tt.want)
+			}
+
+			if _, err := s.Get(ctx, tt.idOrURL); !errors.Is(err, store.ErrVideoNotFound) {
+				t.Errorf("Get after Delete = %v, want %v", err, store.ErrVideoNotFound)
+			}
+		})
+	}
+}
+
+func TestVideosOfOtherModels(t *testing.T) {
+	s := testStore(t)
+	ctx := context.Background()
+
+	if err := s.Store(ctx, testSource("c", "mxbai"), []*video.Frame{testFrame(1, "a green tree", 1)}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	videos, err := s.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	for _, v := range videos {
+		if v.ID == "c" {
+			t.Error("List includes a video of another embedding model")
+		}
+	}
+
+	if _, err := s.Get(ctx, "c"); !errors.Is(err, store.ErrVideoNotFound) {
+		t.Errorf("Get error = %v, want %v", err, store.ErrVideoNotFound)
+	}
+	if _, err := s.Delete(ctx, "c"); !errors.Is(err, store.ErrVideoNotFound) {
+		t.Errorf("Delete error = %v, want %v", err, store.ErrVideoNotFound)
+	}
+	if got := len(s.points); got != 4 {
+		t.Errorf("store has %d points, want 4", got)
+	}
+}
+
+func TestReindex(t *testing.T) {
+	tests := []struct {
+		name      string
+		fromModel string
+		dimension int
+		embedErr  bool
+		want      int
+		wantErr   bool
+	}{
+		{name: "copies every frame", fromModel: "nomic", dimension: 3, want: 6},
+		{name: "unknown model", fromModel: "minilm", dimension: 3, want: 3, wantErr: true},
+		{name: "embedding fails", fromModel: "nomic", dimension: 3, embedErr: true, want: 3, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testStore(t)
+
+			target := reopen(t, s, "mxbai", store.Dimensions{Description: tt.dimension})
+
+			embed := func(ctx context.Context, text string) ([]float32, error) {
+				if tt.embedErr {
+					return nil, context.DeadlineExceeded
+				}
+				return make([]float32, tt.dimension), nil
+			}
+
+			err := target.Reindex(context.Background(), tt.fromModel, embed, func(done, total int) {})
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Reindex error = %v, want error %v", err, tt.wantErr)
+			}
+
+			if got := len(target.points); got != tt.want {
+				t.Errorf("store has %d points, want %d", got, tt.want)
+			}
+			for _, pt := range target.points {
+				if pt.EmbeddingModel == "mxbai" && len(pt.Vector) != tt.dimension {
+					t.Errorf("reindexed point has %d dimensions, want %d", len(pt.Vector), tt.dimension)
+				}
+			}
+		})
+	}
+}
+
+func TestReindexKeepsTargetFrames(t *testing.T) {
+	s := testStore(t)
+	ctx := context.Background()
+
+	target := reopen(t, s, "mxbai", store.Dimensions{Description: 1})
+	if err := target.Store(ctx, testSource("c", "mxbai"), []*video.Frame{testFrame(1, "a green tree", 1)}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	embed := func(ctx context.Context, text string) ([]float32, error) { return []float32{1}, nil }
+	if err := target.Reindex(ctx, "nomic", embed, func(done, total int) {}); err != nil {
+		t.Fatalf("Reindex: %v", err)
+	}
+
+	res, err := target.Search(ctx, store.VectorDescription, []float32{1}, 10, store.Filter{})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+
+	got := descriptions(res)
+	slices.Sort(got)
+	want := []string{"a blue boat", "a green tree", "a red bike", "a red car"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Search = %q, want %q", got, want)
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	s := testStore(t)
+	ctx := context.Background()
+
+	src := testSource("c", "nomic")
+	src.Metadata = &video.Metadata{Title: "Trees"}
+	if err := s.Store(ctx, src, []*video.Frame{testFrame(1, "a green tree", 1, 0), testFrame(2, "a tall tree", 1, 0)}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	reopened := reopen(t, s, "nomic", store.Dimensions{Description: 2})
+	if got := len(reopened.catalog); got != 1 {
+		t.Fatalf("catalog has %d records, want 1", got)
+	}
+
+	res, err := reopened.Search(ctx, store.VectorDescription, []float32{1, 0}, 5, store.Filter{Videos: []string{"c"}})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	for _, r := range res {
+		if r.Metadata == nil || r.Metadata.Title != "Trees" {
+			t.Errorf("result %q has metadata %+v", r.Description, r.Metadata)
+		}
+	}
+
+	summary, err := reopened.Get(ctx, "c")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if summary.Metadata == nil || summary.Metadata.Title != "Trees" {
+		t.Errorf("summary has metadata %+v", summary.Metadata)
+	}
+
+	if _, err := reopened.Delete(ctx, "c"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if got := len(reopened.catalog); got != 0 {
+		t.Errorf("catalog has %d records after deleting the video, want 0", got)
+	}
+}
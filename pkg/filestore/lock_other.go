@@ -0,0 +1,30 @@
+//go:build !unix
+
+package filestore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// lockFile creates the file at path, failing if it already exists. A store
+// that was not closed leaves it behind, and it has to be removed by hand.
+func lockFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return nil, errLocked
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to create lock file: %w", err)
+	}
+
+	return f, nil
+}
+
+func unlockFile(f *os.File) error {
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(f.Name())
+}
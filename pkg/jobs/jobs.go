@@ -0,0 +1,392 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/cmd"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
+)
+
+type State string
+
+const (
+	StateQueued    State = "queued"
+	StateRunning   State = "running"
+	StateSucceeded State = "succeeded"
+	StateFailed    State = "failed"
+	StateCancelled State = "cancelled"
+)
+
+// Kind selects what a job does with its input.
+type Kind string
+
+const (
+	// KindProcess indexes the video or directory named by the input.
+	KindProcess Kind = "process"
+	// KindReindex re-embeds the frames of the embedding model named by the
+	// input.
+	KindReindex Kind = "reindex"
+)
+
+func (k Kind) valid() bool {
+	return k == KindProcess || k == KindReindex
+}
+
+var (
+	ErrNotFound = errors.New("job not found")
+	ErrFinished = errors.New("job already finished")
+)
+
+type Job struct {
+	ID          string           `json:"id"`
+	Kind        Kind             `json:"kind"`
+	Input       string           `json:"input"`
+	Options     config.Overrides `json:"options"`
+	State       State            `json:"state"`
+	Stage       cmd.Stage        `json:"stage,omitempty"`
+	Video       string           `json:"video,omitempty"`
+	FramesDone  int              `json:"frames_done"`
+	FramesTotal int              `json:"frames_total"`
+	ETASeconds  float64          `json:"eta_seconds,omitempty"`
+	URLs        []string         `json:"urls,omitempty"`
+	Errors      []string         `json:"errors,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+func (j *Job) finished() bool {
+	return j.State == StateSucceeded || j.State == StateFailed || j.State == StateCancelled
+}
+
+func (j *Job) snapshot() Job {
+	res := *j
+	res.URLs = slices.Clone(j.URLs)
+	res.Errors = slices.Clone(j.Errors)
+
+	return res
+}
+
+// RunFunc processes a job, reporting progress through events.
+type RunFunc func(ctx context.Context, job Job, events cmd.EventFunc) ([]string, error)
+
+// Manager runs jobs on a fixed number of workers. Every job is persisted to a
+// local file so queued and interrupted jobs are resumed after a restart.
+type Manager struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	path    string
+	run     RunFunc
+	jobs    map[string]*Job
+	pending []string
+	cancels map[string]context.CancelFunc
+	subs    map[string]map[chan Job]struct{}
+}
+
+func New(path string, workers int, run RunFunc) (*Manager, error) {
+	if workers < 1 {
+		return nil, fmt.Errorf("workers must be positive, got %d", workers)
+	}
+
+	m := &Manager{
+		path:    path,
+		run:     run,
+		jobs:    map[string]*Job{},
+		cancels: map[string]context.CancelFunc{},
+		subs:    map[string]map[chan Job]struct{}{},
+	}
+	m.cond = sync.NewCond(&m.mu)
+
+	if err := m.load(); err != nil {
+		return nil, fmt.Errorf("failed to load jobs: %w", err)
+	}
+
+	for range workers {
+		go m.work()
+	}
+
+	return m, nil
+}
+
+// Submit queues a new job of the given kind for input.
+func (m *Manager) Submit(kind Kind, input string, options config.Overrides) (Job, error) {
+	if !kind.valid() {
+		return Job{}, fmt.Errorf("unknown job kind %q", kind)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	job := &Job{
+		ID:        uuid.NewString(),
+		Kind:      kind,
+		Input:     input,
+		Options:   options,
+		State:     StateQueued,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	m.jobs[job.ID] = job
+	if err := m.save(); err != nil {
+		delete(m.jobs, job.ID)
+		return Job{}, fmt.Errorf("failed to save jobs: %w", err)
+	}
+
+	m.pending = append(m.pending, job.ID)
+	m.cond.Signal()
+
+	return job.snapshot(), nil
+}
+
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+
+	return job.snapshot(), nil
+}
+
+// List returns every known job, newest first.
+func (m *Manager) List() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res := make([]Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		res = append(res, job.snapshot())
+	}
+
+	slices.SortFunc(res, func(a, b Job) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	return res
+}
+
+// Cancel stops a queued or running job.
+func (m *Manager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	} else if job.finished() {
+		return job.snapshot(), ErrFinished
+	}
+
+	if cancel, ok := m.cancels[id]; ok {
+		// the worker records the final state once the run returns
+		cancel()
+		return job.snapshot(), nil
+	}
+
+	m.pending = slices.DeleteFunc(m.pending, func(p string) bool { return p == id })
+	m.update(job, func(j *Job) { j.State = StateCancelled })
+
+	return job.snapshot(), nil
+}
+
+// Subscribe returns a channel receiving a snapshot of the job whenever it
+// changes. The channel is closed once the job finishes; slow subscribers may
+// miss intermediate snapshots. Call the returned function to unsubscribe.
+func (m *Manager) Subscribe(id string) (<-chan Job, func(), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, nil, ErrNotFound
+	}
+
+	ch := make(chan Job, 16)
+	ch <- job.snapshot()
+
+	if job.finished() {
+		close(ch)
+		return ch, func() {}, nil
+	}
+
+	if m.subs[id] == nil {
+		m.subs[id] = map[chan Job]struct{}{}
+	}
+	m.subs[id][ch] = struct{}{}
+
+	unsubscribe := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		if _, ok := m.subs[id][ch]; ok {
+			delete(m.subs[id], ch)
+			close(ch)
+		}
+	}
+
+	return ch, unsubscribe, nil
+}
+
+func (m *Manager) work() {
+	for {
+		m.mu.Lock()
+		for len(m.pending) == 0 {
+			m.cond.Wait()
+		}
+
+		job := m.jobs[m.pending[0]]
+		m.pending = m.pending[1:]
+
+		ctx, cancel := context.WithCancel(context.Background())
+		m.cancels[job.ID] = cancel
+		m.update(job, func(j *Job) {
+			j.State = StateRunning
+			j.FramesDone = 0
+			j.FramesTotal = 0
+			j.Errors = nil
+		})
+		snapshot := job.snapshot()
+		m.mu.Unlock()
+
+		urls, err := m.run(ctx, snapshot, func(e cmd.Event) {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+
+			job.Stage = e.Stage
+			job.Video = e.Video
+			job.FramesDone = e.FramesDone
+			job.FramesTotal = e.FramesTotal
+			job.ETASeconds = e.ETA.Seconds()
+			job.UpdatedAt = time.Now()
+
+			if e.Err != nil {
+				m.update(job, func(j *Job) { j.Errors = append(j.Errors, e.Err.Error()) })
+			} else {
+				m.notify(job)
+			}
+		})
+
+		m.mu.Lock()
+		delete(m.cancels, job.ID)
+		m.update(job, func(j *Job) {
+			j.URLs = urls
+			j.ETASeconds = 0
+			switch {
+			case ctx.Err() != nil:
+				j.State = StateCancelled
+			case err != nil:
+				j.State = StateFailed
+				j.Errors = append(j.Errors, err.Error())
+			default:
+				j.State = StateSucceeded
+			}
+		})
+		m.mu.Unlock()
+		cancel()
+	}
+}
+
+// update applies fn to job, persists the result and notifies subscribers.
+// The caller must hold mu.
+func (m *Manager) update(job *Job, fn func(j *Job)) {
+	fn(job)
+	job.UpdatedAt = time.Now()
+
+	if err := m.save(); err != nil {
+		log.Printf("failed to save jobs: %v", err)
+	}
+
+	m.notify(job)
+}
+
+// notify sends a snapshot of job to its subscribers, closing their channels
+// once the job has finished. The caller must hold mu.
+func (m *Manager) notify(job *Job) {
+	snapshot := job.snapshot()
+
+	for ch := range m.subs[job.ID] {
+		select {
+		case ch <- snapshot:
+		default:
+		}
+
+		if job.finished() {
+			close(ch)
+		}
+	}
+
+	if job.finished() {
+		delete(m.subs, job.ID)
+	}
+}
+
+func (m *Manager) load() error {
+	data, err := os.ReadFile(m.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var jobs []*Job
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return err
+	}
+
+	slices.SortFunc(jobs, func(a, b *Job) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	for _, job := range jobs {
+		if !job.Kind.valid() {
+			return fmt.Errorf("job %s has unknown kind %q", job.ID, job.Kind)
+		}
+
+		m.jobs[job.ID] = job
+
+		if !job.finished() {
+			log.Printf("resuming job %s for %s", job.ID, job.Input)
+			job.State = StateQueued
+			m.pending = append(m.pending, job.ID)
+		}
+	}
+
+	return nil
+}
+
+// save writes every job to the jobs file. The caller must hold mu.
+func (m *Manager) save() error {
+	jobs := make([]*Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, job)
+	}
+
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return err
+	}
+
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, m.path)
+}
@@ -0,0 +1,240 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/cmd"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
+)
+
+// wait polls the job until it reaches the given state.
+func wait(t *testing.T, m *Manager, id string, state State) Job {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := m.Get(id)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if job.State == state {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s is %s, want %s", id, job.State, state)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(filepath.Join(t.TempDir(), "jobs.json"), 0, nil); err == nil {
+		t.Error("New succeeded without workers")
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		run        RunFunc
+		want       State
+		wantURLs   []string
+		wantErrors []string
+	}{
+		{
+			name: "succeeds",
+			run: func(ctx context.Context, job Job, events cmd.EventFunc) ([]string, error) {
+				events(cmd.Event{FramesDone: 1, FramesTotal: 2})
+				events(cmd.Event{FramesDone: 2, FramesTotal: 2})
+				return []string{job.Input}, nil
+			},
+			want:     StateSucceeded,
+			wantURLs: []string{"video.mp4"},
+		},
+		{
+			name: "records frame errors",
+			run: func(ctx context.Context, job Job, events cmd.EventFunc) ([]string, error) {
+				events(cmd.Event{FramesDone: 2, FramesTotal: 2, Err: errors.New("frame failed")})
+				return []string{job.Input}, nil
+			},
+			want:       StateSucceeded,
+			wantURLs:   []string{"video.mp4"},
+			wantErrors: []string{"frame failed"},
+		},
+		{
+			name: "fails",
+			run: func(ctx context.Context, job Job, events cmd.EventFunc) ([]string, error) {
+				return nil, errors.New("download failed")
+			},
+			want:       StateFailed,
+			wantErrors: []string{"download failed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(filepath.Join(t.TempDir(), "jobs.json"), 1, tt.run)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+
+			job, err := m.Submit(KindProcess, "video.mp4", config.Overrides{})
+			if err != nil {
+				t.Fatalf("Submit: %v", err)
+			}
+
+			job = wait(t, m, job.ID, tt.want)
+			if !slices.Equal(job.URLs, tt.wantURLs) {
+				t.Errorf("URLs = %q, want %q", job.URLs, tt.wantURLs)
+			}
+			if !slices.Equal(job.Errors, tt.wantErrors) {
+				t.Errorf("Errors = %q, want %q", job.Errors, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestCancel(t *testing.T) {
+	started := make(chan struct{})
+	m, err := New(filepath.Join(t.TempDir(), "jobs.json"), 1, func(ctx context.Context, job Job, events cmd.EventFunc) ([]string, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	running, err := m.Submit(KindProcess, "a.mp4", config.Overrides{})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	<-started
+
+	queued, err := m.Submit(KindProcess, "b.mp4", config.Overrides{})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	// the queued job is cancelled at once, without waiting for a worker
+	if job, err := m.Cancel(queued.ID); err != nil || job.State != StateCancelled {
+		t.Errorf("Cancel of queued job = %s, %v, want %s", job.State, err, StateCancelled)
+	}
+
+	if _, err := m.Cancel(running.ID); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	wait(t, m, running.ID, StateCancelled)
+
+	if _, err := m.Cancel(running.ID); !errors.Is(err, ErrFinished) {
+		t.Errorf("Cancel of finished job = %v, want %v", err, ErrFinished)
+	}
+	if _, err := m.Cancel("unknown"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Cancel of unknown job = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestList(t *testing.T) {
+	m, err := New(filepath.Join(t.TempDir(), "jobs.json"), 1, func(ctx context.Context, job Job, events cmd.EventFunc) ([]string, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	var want []string
+	for _, input := range []string{"a.mp4", "b.mp4", "c.mp4"} {
+		job, err := m.Submit(KindProcess, input, config.Overrides{})
+		if err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+		want = slices.Insert(want, 0, job.Input)
+
+		// keep the creation times apart
+		time.Sleep(time.Millisecond)
+	}
+
+	var got []string
+	for _, job := range m.List() {
+		got = append(got, job.Input)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("List = %q, want %q", got, want)
+	}
+}
+
+func TestResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	data, err := json.Marshal([]*Job{
+		{ID: "done", Kind: KindProcess, Input: "a.mp4", State: StateSucceeded, CreatedAt: created},
+		{ID: "running", Kind: KindProcess, Input: "b.mp4", State: StateRunning, FramesDone: 3, CreatedAt: created.Add(time.Second)},
+		{ID: "queued", Kind: KindProcess, Input: "c.mp4", State: StateQueued, CreatedAt: created.Add(2 * time.Second)},
+	})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	inputs := make(chan string, 3)
+	m, err := New(path, 1, func(ctx context.Context, job Job, events cmd.EventFunc) ([]string, error) {
+		inputs <- job.Input
+		return []string{job.Input}, nil
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	wait(t, m, "running", StateSucceeded)
+	wait(t, m, "queued", StateSucceeded)
+	if job := wait(t, m, "done", StateSucceeded); job.URLs != nil {
+		t.Errorf("finished job was run again, URLs = %q", job.URLs)
+	}
+
+	// interrupted jobs run again in the order they were created
+	if got := []string{<-inputs, <-inputs}; !slices.Equal(got, []string{"b.mp4", "c.mp4"}) {
+		t.Errorf("resumed inputs = %q", got)
+	}
+
+	reopened, err := New(path, 1, func(ctx context.Context, job Job, events cmd.EventFunc) ([]string, error) {
+		t.Errorf("finished job %s was run after a restart", job.Input)
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if got := len(reopened.List()); got != 3 {
+		t.Errorf("reopened manager has %d jobs, want 3", got)
+	}
+}
+
+func TestKindRequired(t *testing.T) {
+	m, err := New(filepath.Join(t.TempDir(), "jobs.json"), 1, func(ctx context.Context, job Job, events cmd.EventFunc) ([]string, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err := m.Submit("", "a.mp4", config.Overrides{}); err == nil {
+		t.Error("Submit accepted a job without a kind")
+	}
+
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	if err := os.WriteFile(path, []byte(`[{"id": "old", "input": "a.mp4", "state": "queued"}]`), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := New(path, 1, nil); err == nil {
+		t.Error("New loaded a job without a kind")
+	}
+}
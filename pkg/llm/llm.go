@@ -0,0 +1,106 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/ollama"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/openai"
+)
+
+const (
+	BackendOllama = "ollama"
+	BackendOpenAI = "openai"
+)
+
+var Backends = []string{BackendOllama, BackendOpenAI}
+
+// VisionDescriber answers a prompt about an image, either in free text or as
+// JSON matching a schema.
+type VisionDescriber interface {
+	DescribeImage(ctx context.Context, prompt string, image []byte) (string, error)
+	AnalyzeImage(ctx context.Context, prompt string, image []byte, schema map[string]any) (string, error)
+}
+
+// TextGenerator answers a text prompt.
+type TextGenerator interface {
+	Generate(ctx context.Context, prompt string) (string, error)
+}
+
+// Embedder turns text into a vector for similarity search.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// ImageEmbedder embeds images and text into the same space, like CLIP, so
+// text can retrieve images directly.
+type ImageEmbedder interface {
+	Embedder
+	EmbedImage(ctx context.Context, image []byte) ([]float32, error)
+}
+
+// Models holds the backend used for each role. Image is nil unless an image
+// embedding endpoint is configured.
+type Models struct {
+	Vision    VisionDescriber
+	Text      TextGenerator
+	Embedding Embedder
+	Image     ImageEmbedder
+}
+
+type backend interface {
+	VisionDescriber
+	TextGenerator
+	Embedder
+}
+
+var (
+	_ backend       = (*ollama.Client)(nil)
+	_ backend       = (*openai.Client)(nil)
+	_ ImageEmbedder = (*openai.Client)(nil)
+)
+
+// New creates the backend configured for each role: the sampling model
+// describes frames, the query model rewrites queries and the embedding model
+// embeds descriptions.
+func New(cfg *config.Config) (Models, error) {
+	vision, err := newBackend(cfg, cfg.VisionBackend, cfg.SamplingModel)
+	if err != nil {
+		return Models{}, fmt.Errorf("vision backend: %w", err)
+	}
+
+	text, err := newBackend(cfg, cfg.TextBackend, cfg.QueryModel)
+	if err != nil {
+		return Models{}, fmt.Errorf("text backend: %w", err)
+	}
+
+	embedding, err := newBackend(cfg, cfg.EmbeddingBackend, cfg.EmbeddingModel)
+	if err != nil {
+		return Models{}, fmt.Errorf("embedding backend: %w", err)
+	}
+
+	models := Models{
+		Vision:    vision,
+		Text:      text,
+		Embedding: embedding,
+	}
+
+	if cfg.ImageEmbeddingURL != "" {
+		models.Image = openai.New(cfg.ImageEmbeddingURL, cfg.OpenAIKey, cfg.ImageEmbeddingModel, cfg.RateLimit)
+	}
+
+	return models, nil
+}
+
+func newBackend(cfg *config.Config, name, model string) (backend, error) {
+	switch name {
+	case BackendOllama, "":
+		return ollama.New(cfg.OllamaURL, model, cfg.RateLimit), nil
+	case BackendOpenAI:
+		return openai.New(cfg.OpenAIURL, cfg.OpenAIKey, model, cfg.RateLimit), nil
+	default:
+		return nil, fmt.Errorf("unknown backend %q, must be one of %s", name, strings.Join(Backends, ", "))
+	}
+}
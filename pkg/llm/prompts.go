@@ -0,0 +1,127 @@
+package llm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
+)
+
+// DefaultPrompt selects a built-in template instead of a file.
+const DefaultPrompt = "default"
+
+//go:embed prompts/*.tmpl
+var builtinPrompts embed.FS
+
+// PromptData holds what a prompt template can refer to. Fields that do not
+// apply, such as Query in a frame prompt, are empty.
+type PromptData struct {
+	// Timestamp is the position of the frame in the video, e.g. 1m30s, and
+	// Seconds the same in seconds.
+	Timestamp string
+	Seconds   float64
+	// Title is the title of the video, if known.
+	Title string
+	// Transcript is what is said while the frame is shown and Previous the
+	// description of the frame before. Both are only set in fusion mode.
+	Transcript string
+	Previous   string
+	// Query is the search query being rewritten, and Count the number of
+	// paraphrases asked for.
+	Query string
+	Count int
+}
+
+// Prompt is a parsed text/template prompt. Name and Hash are stored with
+// every frame described with it, so descriptions can be traced back to the
+// exact template that produced them.
+type Prompt struct {
+	Name string
+	// Hash is the start of the SHA-256 of the template source.
+	Hash string
+	tmpl *template.Template
+}
+
+// Prompts holds the template used for each prompt.
+type Prompts struct {
+	// Frame asks the vision model for a frame description.
+	Frame *Prompt
+	// Query asks the text model for a hypothetical frame description
+	// matching a search query, which embeds closer to the stored
+	// descriptions than the query does.
+	Query *Prompt
+	// Paraphrase asks the text model for several such descriptions, one
+	// per line, for multi-query search.
+	Paraphrase *Prompt
+}
+
+// LoadPrompts loads the templates selected in cfg.
+func LoadPrompts(cfg *config.Config) (Prompts, error) {
+	frame, err := LoadPrompt("frame", cfg.FramePrompt)
+	if err != nil {
+		return Prompts{}, err
+	}
+
+	query, err := LoadPrompt("query", cfg.QueryPrompt)
+	if err != nil {
+		return Prompts{}, err
+	}
+
+	paraphrase, err := LoadPrompt("paraphrase", cfg.ParaphrasePrompt)
+	if err != nil {
+		return Prompts{}, err
+	}
+
+	return Prompts{Frame: frame, Query: query, Paraphrase: paraphrase}, nil
+}
+
+// LoadPrompt parses the template file at path, or the built-in template of
+// the given kind if path is empty or DefaultPrompt. The prompt is named after
+// the file without its extension.
+func LoadPrompt(kind, path string) (*Prompt, error) {
+	name := DefaultPrompt
+
+	var (
+		src []byte
+		err error
+	)
+	if path == "" || path == DefaultPrompt {
+		src, err = builtinPrompts.ReadFile("prompts/" + kind + ".tmpl")
+	} else {
+		src, err = os.ReadFile(path)
+		name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s prompt: %w", kind, err)
+	}
+
+	tmpl, err := template.New(name).Parse(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s prompt: %w", kind, err)
+	}
+
+	sum := sha256.Sum256(src)
+
+	return &Prompt{
+		Name: name,
+		Hash: hex.EncodeToString(sum[:6]),
+		tmpl: tmpl,
+	}, nil
+}
+
+// Render executes the template with data.
+func (p *Prompt) Render(data PromptData) (string, error) {
+	var buf bytes.Buffer
+	if err := p.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt %s: %w", p.Name, err)
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
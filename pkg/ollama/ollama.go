@@ -8,63 +8,70 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 
-	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/ratelimit"
 )
 
-func GetDescriptionFromImage(ctx context.Context, cfg *config.Config, data []byte) (string, error) {
-	payload := map[string]any{
-		"model":  cfg.SamplingModel,
-		"prompt": "Describe this video frame in detail for search purposes. Include objects, actions, colors, and context.",
-		"stream": false,
-		"images": []string{base64.StdEncoding.EncodeToString(data)},
-	}
+// Client talks to an Ollama server using a single model.
+type Client struct {
+	URL   string
+	Model string
+	// RateLimit caps requests per second to the server; zero disables it.
+	RateLimit float64
+}
 
-	rep, err := request(ctx, cfg, "/api/generate", payload)
-	if err != nil {
-		return "", err
+func New(url, model string, rateLimit float64) *Client {
+	return &Client{
+		URL:       url,
+		Model:     model,
+		RateLimit: rateLimit,
 	}
+}
 
-	var res struct {
-		Response string `json:"response"`
-	}
-	if err := json.Unmarshal(rep, &res); err != nil {
-		return "", fmt.Errorf("failed to decode description response: %w", err)
+func (c *Client) DescribeImage(ctx context.Context, prompt string, image []byte) (string, error) {
+	payload := map[string]any{
+		"model":  c.Model,
+		"prompt": prompt,
+		"stream": false,
+		"images": []string{base64.StdEncoding.EncodeToString(image)},
 	}
 
-	return res.Response, nil
+	return c.generate(ctx, payload)
 }
 
-func GetDescriptionFromQuery(ctx context.Context, cfg *config.Config, text string) (string, error) {
+// AnalyzeImage answers with JSON matching schema, using Ollama's structured
+// outputs.
+func (c *Client) AnalyzeImage(ctx context.Context, prompt string, image []byte, schema map[string]any) (string, error) {
 	payload := map[string]any{
-		"model":  cfg.QueryModel,
-		"prompt": fmt.Sprintf("Create a description of an image using the following query that we can use to search in our vector database: %s", text),
+		"model":  c.Model,
+		"prompt": prompt,
 		"stream": false,
+		"images": []string{base64.StdEncoding.EncodeToString(image)},
+		"format": schema,
 	}
 
-	rep, err := request(ctx, cfg, "/api/generate", payload)
-	if err != nil {
-		return "", err
-	}
+	return c.generate(ctx, payload)
+}
 
-	var res struct {
-		Response string `json:"response"`
-	}
-	if err := json.Unmarshal(rep, &res); err != nil {
-		return "", fmt.Errorf("failed to decode description response: %w", err)
+func (c *Client) Generate(ctx context.Context, prompt string) (string, error) {
+	payload := map[string]any{
+		"model":  c.Model,
+		"prompt": prompt,
+		"stream": false,
 	}
 
-	return res.Response, nil
+	return c.generate(ctx, payload)
 }
 
-func GetTextEmbedding(ctx context.Context, cfg *config.Config, text string) ([]float32, error) {
+func (c *Client) Embed(ctx context.Context, text string) ([]float32, error) {
 	payload := map[string]any{
-		"model":  cfg.EmbeddingModel,
+		"model":  c.Model,
 		"prompt": text,
 	}
 
-	rep, err := request(ctx, cfg, "/api/embeddings", payload)
+	rep, err := c.request(ctx, "/api/embeddings", payload)
 	if err != nil {
 		return nil, err
 	}
@@ -79,16 +86,39 @@ func GetTextEmbedding(ctx context.Context, cfg *config.Config, text string) ([]f
 	return res.Embedding, nil
 }
 
-func request(ctx context.Context, cfg *config.Config, endpoint string, payload any) ([]byte, error) {
+func (c *Client) generate(ctx context.Context, payload map[string]any) (string, error) {
+	rep, err := c.request(ctx, "/api/generate", payload)
+	if err != nil {
+		return "", err
+	}
+
+	var res struct {
+		Response string `json:"response"`
+	}
+	if err := json.Unmarshal(rep, &res); err != nil {
+		return "", fmt.Errorf("failed to decode description response: %w", err)
+	}
+
+	return res.Response, nil
+}
+
+func (c *Client) request(ctx context.Context, endpoint string, payload any) ([]byte, error) {
 	payloadJSON, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	endpointURL := fmt.Sprintf("%s%s", c.URL, endpoint)
+	if u, err := url.Parse(endpointURL); err == nil {
+		if err := ratelimit.Wait(ctx, u.Host, c.RateLimit); err != nil {
+			return nil, err
+		}
+	}
+
 	req, err := http.NewRequestWithContext(
 		ctx,
 		"POST",
-		fmt.Sprintf("%s%s", cfg.OllamaURL, endpoint),
+		endpointURL,
 		bytes.NewBuffer(payloadJSON),
 	)
 	if err != nil {
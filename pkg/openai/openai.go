@@ -0,0 +1,194 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/ratelimit"
+)
+
+// Client talks to a server implementing the OpenAI API, such as vLLM,
+// llama.cpp server or LocalAI, using a single model.
+type Client struct {
+	// URL is the server's base URL, without the /v1 suffix.
+	URL    string
+	APIKey string
+	Model  string
+	// RateLimit caps requests per second to the server; zero disables it.
+	RateLimit float64
+}
+
+func New(url, apiKey, model string, rateLimit float64) *Client {
+	return &Client{
+		URL:       strings.TrimSuffix(strings.TrimSuffix(url, "/"), "/v1"),
+		APIKey:    apiKey,
+		Model:     model,
+		RateLimit: rateLimit,
+	}
+}
+
+type contentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *imageURL `json:"image_url,omitempty"`
+}
+
+type imageURL struct {
+	URL string `json:"url"`
+}
+
+func (c *Client) DescribeImage(ctx context.Context, prompt string, image []byte) (string, error) {
+	content := []contentPart{
+		{Type: "text", Text: prompt},
+		{Type: "image_url", ImageURL: &imageURL{URL: dataURI(image)}},
+	}
+
+	return c.chat(ctx, content, nil)
+}
+
+// AnalyzeImage answers with JSON matching schema, using the json_schema
+// response format.
+func (c *Client) AnalyzeImage(ctx context.Context, prompt string, image []byte, schema map[string]any) (string, error) {
+	content := []contentPart{
+		{Type: "text", Text: prompt},
+		{Type: "image_url", ImageURL: &imageURL{URL: dataURI(image)}},
+	}
+
+	return c.chat(ctx, content, map[string]any{
+		"type": "json_schema",
+		"json_schema": map[string]any{
+			"name":   "frame_analysis",
+			"schema": schema,
+		},
+	})
+}
+
+func (c *Client) Generate(ctx context.Context, prompt string) (string, error) {
+	return c.chat(ctx, prompt, nil)
+}
+
+func (c *Client) Embed(ctx context.Context, text string) ([]float32, error) {
+	return c.embed(ctx, map[string]any{
+		"model": c.Model,
+		"input": text,
+	})
+}
+
+// EmbedImage embeds an image passed as a data URI, which multimodal embedding
+// servers such as Infinity accept in place of text.
+func (c *Client) EmbedImage(ctx context.Context, image []byte) ([]float32, error) {
+	return c.embed(ctx, map[string]any{
+		"model":    c.Model,
+		"input":    dataURI(image),
+		"modality": "image",
+	})
+}
+
+func (c *Client) embed(ctx context.Context, payload map[string]any) ([]float32, error) {
+	rep, err := c.request(ctx, "/v1/embeddings", payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rep, &res); err != nil {
+		return nil, fmt.Errorf("failed to decode embedding response: %w", err)
+	} else if len(res.Data) == 0 {
+		return nil, fmt.Errorf("embedding response has no data")
+	}
+
+	return res.Data[0].Embedding, nil
+}
+
+// chat sends a single user message, either plain text or content parts, and
+// returns the reply.
+func (c *Client) chat(ctx context.Context, content any, responseFormat map[string]any) (string, error) {
+	payload := map[string]any{
+		"model": c.Model,
+		"messages": []map[string]any{
+			{"role": "user", "content": content},
+		},
+	}
+	if responseFormat != nil {
+		payload["response_format"] = responseFormat
+	}
+
+	rep, err := c.request(ctx, "/v1/chat/completions", payload)
+	if err != nil {
+		return "", err
+	}
+
+	var res struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(rep, &res); err != nil {
+		return "", fmt.Errorf("failed to decode chat response: %w", err)
+	} else if len(res.Choices) == 0 {
+		return "", fmt.Errorf("chat response has no choices")
+	}
+
+	return res.Choices[0].Message.Content, nil
+}
+
+func (c *Client) request(ctx context.Context, endpoint string, payload any) ([]byte, error) {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	endpointURL := fmt.Sprintf("%s%s", c.URL, endpoint)
+	if u, err := url.Parse(endpointURL); err == nil {
+		if err := ratelimit.Wait(ctx, u.Host, c.RateLimit); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		endpointURL,
+		bytes.NewBuffer(payloadJSON),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	client := &http.Client{Timeout: 120 * time.Second}
+
+	rep, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("api request failed: %w", err)
+	}
+	defer rep.Body.Close()
+
+	if rep.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(rep.Body)
+		return nil, fmt.Errorf("api error: %s (%d)", string(body), rep.StatusCode)
+	}
+
+	return io.ReadAll(rep.Body)
+}
+
+func dataURI(image []byte) string {
+	return "data:" + http.DetectContentType(image) + ";base64," + base64.StdEncoding.EncodeToString(image)
+}
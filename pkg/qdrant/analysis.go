@@ -0,0 +1,117 @@
+package qdrant
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/store"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/video"
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// analysisPayload adds the fields of a structured analysis to a payload.
+func analysisPayload(payload map[string]any, a *video.Analysis) {
+	if a == nil {
+		return
+	}
+
+	maps.Copy(payload, map[string]any{
+		"summary":        a.Summary,
+		"objects":        anySlice(a.Objects),
+		"people_count":   a.PeopleCount,
+		"on_screen_text": a.OnScreenText,
+		"has_text":       a.OnScreenText != "",
+		"scene_type":     a.SceneType,
+		"colors":         anySlice(a.Colors),
+	})
+}
+
+// parseAnalysis reads the structured analysis of a payload, or nil if the
+// frame was not processed in structured mode.
+func parseAnalysis(payload map[string]*qdrant.Value) *video.Analysis {
+	if _, ok := payload["scene_type"]; !ok {
+		return nil
+	}
+
+	return &video.Analysis{
+		Summary:      payload["summary"].GetStringValue(),
+		Objects:      stringList(payload["objects"]),
+		PeopleCount:  int(payload["people_count"].GetIntegerValue()),
+		OnScreenText: payload["on_screen_text"].GetStringValue(),
+		SceneType:    payload["scene_type"].GetStringValue(),
+		Colors:       stringList(payload["colors"]),
+	}
+}
+
+// analysisConditions translates the structured fields of a filter.
+func analysisConditions(filter store.Filter) []*qdrant.Condition {
+	var must []*qdrant.Condition
+
+	objects, sceneTypes, colors := filter.Labels()
+	for _, object := range objects {
+		must = append(must, qdrant.NewMatch("objects", object))
+	}
+	if len(sceneTypes) > 0 {
+		must = append(must, qdrant.NewMatchKeywords("scene_type", sceneTypes...))
+	}
+	if len(colors) > 0 {
+		must = append(must, qdrant.NewMatchKeywords("colors", colors...))
+	}
+	if filter.HasText != nil {
+		must = append(must, qdrant.NewMatchBool("has_text", *filter.HasText))
+	}
+	if filter.MinPeople != nil || filter.MaxPeople != nil {
+		people := &qdrant.Range{}
+		if filter.MinPeople != nil {
+			people.Gte = qdrant.PtrOf(float64(*filter.MinPeople))
+		}
+		if filter.MaxPeople != nil {
+			people.Lte = qdrant.PtrOf(float64(*filter.MaxPeople))
+		}
+		must = append(must, qdrant.NewRange("people_count", people))
+	}
+
+	return must
+}
+
+func (c *Client) Facets(ctx context.Context, filter store.Filter, limit int) (*store.Facets, error) {
+	state := c.currentState()
+	if state.stale != nil {
+		return nil, state.stale
+	}
+	if limit < 1 {
+		return nil, fmt.Errorf("limit must be positive, got %d", limit)
+	}
+
+	var res store.Facets
+	if state.missing {
+		return &res, nil
+	}
+	for key, counts := range map[string]*[]store.FacetCount{
+		"objects":    &res.Objects,
+		"scene_type": &res.SceneTypes,
+		"colors":     &res.Colors,
+	} {
+		hits, err := c.Facet(ctx, &qdrant.FacetCounts{
+			CollectionName: c.collection,
+			Key:            key,
+			Filter:         searchFilter(filter),
+			Limit:          qdrant.PtrOf(uint64(limit)),
+			Exact:          qdrant.PtrOf(true),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", key, err)
+		}
+
+		*counts = make([]store.FacetCount, 0, len(hits))
+		for _, hit := range hits {
+			*counts = append(*counts, store.FacetCount{
+				Value: hit.GetValue().GetStringValue(),
+				Count: hit.GetCount(),
+			})
+		}
+	}
+
+	return &res, nil
+}
@@ -0,0 +1,251 @@
+package qdrant
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/store"
+	"github.com/qdrant/go-client/qdrant"
+)
+
+const collectionPrefix = "llm-video-analyzer-frames"
+
+// LegacyModel selects the collection of earlier versions as the source of a
+// reindex. It held the frames of a single embedding model under one unnamed
+// vector, so its descriptions are embedded again like those of any model.
+const LegacyModel = "legacy"
+
+var unsafeCollectionChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// CollectionName returns the alias under which frames embedded with model are
+// stored. The alias points at a timestamped collection so it can be swapped
+// atomically.
+func CollectionName(model string) string {
+	return collectionPrefix + "-" + modelSlug(model)
+}
+
+// modelSlug turns a model name into a part of a collection name.
+func modelSlug(model string) string {
+	model = strings.TrimSuffix(strings.ToLower(model), ":latest")
+
+	return strings.Trim(unsafeCollectionChars.ReplaceAllString(model, "-"), "-")
+}
+
+// ensureCollection creates the collection of the client's model if it does
+// not exist yet, and returns the state of the collection.
+func (c *Client) ensureCollection(ctx context.Context) (collectionState, error) {
+	c.warnLegacyCollection(ctx)
+
+	state := collectionState{keywords: true}
+
+	target, err := c.aliasTarget(ctx, c.collection)
+	if err != nil {
+		return state, err
+	}
+
+	if target == "" {
+		if c.dimension == 0 {
+			// the collection can only be created once its dimension is known
+			state.missing = true
+			return state, nil
+		}
+
+		name := newCollectionName(c.collection)
+		if err := c.createCollection(ctx, name); err != nil {
+			return state, err
+		}
+
+		return state, c.pointAlias(ctx, c.collection, name)
+	}
+
+	if c.dimension == 0 {
+		return state, c.createIndexes(ctx, target)
+	}
+
+	sizes, err := c.vectorSizes(ctx, target)
+	if err != nil {
+		return state, err
+	}
+
+	if size := sizes[store.VectorDescription]; size != uint64(c.dimension) {
+		state.stale = fmt.Errorf(
+			"collection %q holds %d-dimensional embeddings but the embedding model now produces %d; run reindex --from-model %s to rebuild it",
+			c.collection, size, c.dimension, c.model,
+		)
+		return state, nil
+	}
+
+	if size := sizes[store.VectorImage]; c.imageDimension > 0 && size != uint64(c.imageDimension) {
+		state.images = fmt.Errorf(
+			"%w of %d dimensions in collection %q; run reindex --from-model %s to add it, then process videos again with --force to embed their frames",
+			store.ErrNoImageIndex, c.imageDimension, c.collection, c.model,
+		)
+		log.Print(state.images)
+	}
+
+	if !c.hasKeywords(ctx, target) {
+		state.keywords = false
+		log.Printf("collection %q has no keyword index; run reindex --from-model %s to enable keyword search", c.collection, c.model)
+	}
+
+	return state, c.createIndexes(ctx, target)
+}
+
+// aliasTarget returns the collection alias points at, or "" if there is none.
+func (c *Client) aliasTarget(ctx context.Context, alias string) (string, error) {
+	aliases, err := c.ListAliases(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list aliases: %w", err)
+	}
+
+	for _, a := range aliases {
+		if a.GetAliasName() == alias {
+			return a.GetCollectionName(), nil
+		}
+	}
+
+	return "", nil
+}
+
+// pointAlias points alias at collection in a single atomic update.
+func (c *Client) pointAlias(ctx context.Context, alias, collection string) error {
+	old, err := c.aliasTarget(ctx, alias)
+	if err != nil {
+		return err
+	}
+
+	var ops []*qdrant.AliasOperations
+	if old != "" {
+		ops = append(ops, qdrant.NewAliasDelete(alias))
+	}
+	ops = append(ops, qdrant.NewAliasCreate(alias, collection))
+
+	if err := c.UpdateAliases(ctx, ops); err != nil {
+		return fmt.Errorf("failed to update alias: %w", err)
+	}
+
+	return nil
+}
+
+// vectorSizes returns the size of every named vector of collection.
+func (c *Client) vectorSizes(ctx context.Context, collection string) (map[string]uint64, error) {
+	info, err := c.GetCollectionInfo(ctx, collection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection info: %w", err)
+	}
+
+	params := info.GetConfig().GetParams().GetVectorsConfig().GetParamsMap().GetMap()
+	if _, ok := params[store.VectorDescription]; !ok {
+		return nil, fmt.Errorf("collection %q has no %q vector", collection, store.VectorDescription)
+	}
+
+	res := make(map[string]uint64, len(params))
+	for name, p := range params {
+		res[name] = p.GetSize()
+	}
+
+	return res, nil
+}
+
+// hasKeywords reports whether collection has the sparse keyword vector.
+func (c *Client) hasKeywords(ctx context.Context, collection string) bool {
+	info, err := c.GetCollectionInfo(ctx, collection)
+	if err != nil {
+		return false
+	}
+
+	_, ok := info.GetConfig().GetParams().GetSparseVectorsConfig().GetMap()[store.VectorKeywords]
+
+	return ok
+}
+
+// warnLegacyCollection points out frames stored before collections were kept
+// per embedding model, since they are not searched until they are migrated.
+func (c *Client) warnLegacyCollection(ctx context.Context) {
+	collections, err := c.ListCollections(ctx)
+	if err == nil && slices.Contains(collections, collectionPrefix) {
+		log.Printf("found collection %q from an earlier version; run reindex --from-model %s to migrate its frames", collectionPrefix, LegacyModel)
+	}
+}
+
+// isFrameCollection reports whether name is a collection or alias of frames
+// of any embedding model, or the collection of an earlier version.
+func isFrameCollection(name string) bool {
+	return name == collectionPrefix || strings.HasPrefix(name, collectionPrefix+"-")
+}
+
+// newCollectionName returns a fresh timestamped collection name for alias.
+func newCollectionName(alias string) string {
+	return fmt.Sprintf("%s-%d", alias, time.Now().UnixMilli())
+}
+
+// createCollection creates an empty collection with the payload indexes.
+func (c *Client) createCollection(ctx context.Context, name string) error {
+	vectors := map[string]*qdrant.VectorParams{
+		store.VectorDescription: {
+			Size:     uint64(c.dimension),
+			Distance: qdrant.Distance_Cosine,
+		},
+	}
+	if c.imageDimension > 0 {
+		vectors[store.VectorImage] = &qdrant.VectorParams{
+			Size:     uint64(c.imageDimension),
+			Distance: qdrant.Distance_Cosine,
+		}
+	}
+
+	err := c.Client.CreateCollection(ctx, &qdrant.CreateCollection{
+		CollectionName: name,
+		VectorsConfig:  qdrant.NewVectorsConfigMap(vectors),
+		SparseVectorsConfig: qdrant.NewSparseVectorsConfig(map[string]*qdrant.SparseVectorParams{
+			store.VectorKeywords: {Modifier: qdrant.Modifier_Idf.Enum()},
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.createIndexes(ctx, name)
+}
+
+// createIndexes adds the payload indexes used to filter frames.
+// Creating an index that already exists is a no-op.
+func (c *Client) createIndexes(ctx context.Context, collectionName string) error {
+	indexes := map[string]qdrant.FieldType{
+		"kind":            qdrant.FieldType_FieldTypeKeyword,
+		"url":             qdrant.FieldType_FieldTypeKeyword,
+		"video_id":        qdrant.FieldType_FieldTypeKeyword,
+		"sampling_model":  qdrant.FieldType_FieldTypeKeyword,
+		"embedding_model": qdrant.FieldType_FieldTypeKeyword,
+		"channel":         qdrant.FieldType_FieldTypeKeyword,
+		"tags":            qdrant.FieldType_FieldTypeKeyword,
+		"prompt_hash":     qdrant.FieldType_FieldTypeKeyword,
+		"objects":         qdrant.FieldType_FieldTypeKeyword,
+		"scene_type":      qdrant.FieldType_FieldTypeKeyword,
+		"colors":          qdrant.FieldType_FieldTypeKeyword,
+		"has_text":        qdrant.FieldType_FieldTypeBool,
+		"people_count":    qdrant.FieldType_FieldTypeInteger,
+		"indexed_at":      qdrant.FieldType_FieldTypeDatetime,
+		"timestamp":       qdrant.FieldType_FieldTypeFloat,
+		"end_timestamp":   qdrant.FieldType_FieldTypeFloat,
+	}
+
+	for field, fieldType := range indexes {
+		_, err := c.CreateFieldIndex(ctx, &qdrant.CreateFieldIndexCollection{
+			CollectionName: collectionName,
+			FieldName:      field,
+			FieldType:      qdrant.PtrOf(fieldType),
+			Wait:           qdrant.PtrOf(true),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create %s index: %w", field, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package qdrant
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/store"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/video"
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// catalogKind marks the point holding the catalog record of a video. It has
+// no vectors, so searches never return it.
+const catalogKind = "video"
+
+// newCatalogPoint builds the point holding the catalog record of src. It
+// carries the video's ID and URL so it is found and deleted with its frames.
+func newCatalogPoint(src store.Source) *qdrant.PointStruct {
+	return &qdrant.PointStruct{
+		Id:      qdrant.NewIDUUID(store.CatalogID(src.VideoID).String()),
+		Vectors: qdrant.NewVectorsMap(map[string]*qdrant.Vector{}),
+		Payload: qdrant.NewValueMap(map[string]any{
+			"kind":     catalogKind,
+			"video_id": src.VideoID,
+			"url":      src.URL,
+			"video":    metadataValue(src.Metadata),
+		}),
+	}
+}
+
+// catalogSource returns the catalog record held by a catalog point.
+func catalogSource(payload map[string]*qdrant.Value) (store.Source, bool) {
+	if payload["kind"].GetStringValue() != catalogKind {
+		return store.Source{}, false
+	}
+
+	return store.Source{
+		VideoID:  payload["video_id"].GetStringValue(),
+		URL:      payload["url"].GetStringValue(),
+		Metadata: parseMetadata(payload["video"]),
+	}, true
+}
+
+// catalog returns the catalog records of the given videos by video ID.
+func (c *Client) catalog(ctx context.Context, videoIDs []string) (map[string]*video.Metadata, error) {
+	res := map[string]*video.Metadata{}
+	if len(videoIDs) == 0 {
+		return res, nil
+	}
+
+	ids := make([]*qdrant.PointId, len(videoIDs))
+	for i, id := range videoIDs {
+		ids[i] = qdrant.NewIDUUID(store.CatalogID(id).String())
+	}
+
+	pts, err := c.Client.Get(ctx, &qdrant.GetPoints{
+		CollectionName: c.collection,
+		Ids:            ids,
+		WithPayload:    qdrant.NewWithPayloadInclude("video_id", "video"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get catalog records: %w", err)
+	}
+
+	for _, pt := range pts {
+		payload := pt.GetPayload()
+		res[payload["video_id"].GetStringValue()] = parseMetadata(payload["video"])
+	}
+
+	return res, nil
+}
+
+// metadataValue converts a catalog record into a nested payload object, so
+// its fields can be filtered on like any other payload.
+func metadataValue(m *video.Metadata) any {
+	if m == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil
+	}
+
+	var res map[string]any
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil
+	}
+
+	return res
+}
+
+// parseMetadata reads back a catalog record stored by metadataValue.
+func parseMetadata(value *qdrant.Value) *video.Metadata {
+	fields := value.GetStructValue().GetFields()
+	if len(fields) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(valueToAny(value))
+	if err != nil {
+		return nil
+	}
+
+	var m video.Metadata
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil
+	}
+
+	return &m
+}
+
+func valueToAny(value *qdrant.Value) any {
+	switch v := value.GetKind().(type) {
+	case *qdrant.Value_BoolValue:
+		return v.BoolValue
+	case *qdrant.Value_IntegerValue:
+		return v.IntegerValue
+	case *qdrant.Value_DoubleValue:
+		return v.DoubleValue
+	case *qdrant.Value_StringValue:
+		return v.StringValue
+	case *qdrant.Value_ListValue:
+		res := make([]any, 0, len(v.ListValue.GetValues()))
+		for _, item := range v.ListValue.GetValues() {
+			res = append(res, valueToAny(item))
+		}
+		return res
+	case *qdrant.Value_StructValue:
+		res := make(map[string]any, len(v.StructValue.GetFields()))
+		for key, item := range v.StructValue.GetFields() {
+			res[key] = valueToAny(item)
+		}
+		return res
+	default:
+		return nil
+	}
+}
@@ -1,33 +1,59 @@
 package qdrant
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"net/url"
+	"slices"
 	"strconv"
+	"sync"
+	"time"
 
-	"github.com/google/uuid"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/store"
 	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/video"
 	"github.com/qdrant/go-client/qdrant"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type Client struct {
 	*qdrant.Client
+	// collection is the alias frames are read from and written through.
+	collection string
+	model      string
+	dimension  int
+	// imageDimension is the size of image vectors, or zero when image
+	// embeddings are disabled.
+	imageDimension int
+	// mu guards state, which Cleanup and Reindex replace while other calls
+	// read it.
+	mu    sync.RWMutex
+	state collectionState
 }
 
-type SearchResult struct {
-	Url         string
-	Timestamp   float64
-	Description string
-	Score       float32
+// collectionState is what the client found out about the collection of its
+// model that limits what it can do with it.
+type collectionState struct {
+	// keywords is unset when the collection predates keyword search and has
+	// no sparse vector until it is reindexed.
+	keywords bool
+	// images is set when the collection has no image vector of
+	// imageDimension; image searches fail with it and frames are stored
+	// without one until the collection is reindexed.
+	images error
+	// stale is set when the collection was built for a different dimension;
+	// reads and writes fail with it until the collection is cleaned.
+	stale error
+	// missing is set when nothing was indexed with the model yet and the
+	// collection was not created because the dimension is unknown.
+	missing bool
 }
 
-const (
-	collectionName           = "llm-video-analyzer-frames"
-	collectionDimensionality = 768
-)
+var _ store.VectorStore = (*Client)(nil)
 
-func New(databaseURL string) (*Client, error) {
+// New connects to Qdrant and ensures the collection for the embedding model
+// exists with vectors of the given dimensions.
+func New(databaseURL string, embeddingModel string, dims store.Dimensions) (*Client, error) {
 	u, err := url.Parse(databaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid URL format: %w", err)
@@ -49,96 +75,418 @@ func New(databaseURL string) (*Client, error) {
 		return nil, err
 	}
 
-	res := Client{client}
+	res := &Client{
+		Client:     client,
+		collection: CollectionName(embeddingModel),
+		model:      embeddingModel,
+		dimension:  dims.Description,
+
+		imageDimension: dims.Image,
+	}
 
-	// ensure collection exists
-	ctx := context.Background()
-	exists, err := client.CollectionExists(ctx, collectionName)
+	state, err := res.ensureCollection(context.Background())
 	if err != nil {
 		return nil, err
 	}
+	res.setState(state)
 
-	if !exists {
-		err = res.createCollection(ctx, collectionName)
-		if err != nil {
-			return nil, err
-		}
-	}
+	return res, nil
+}
+
+func (c *Client) currentState() collectionState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 
-	return &res, nil
+	return c.state
 }
 
+func (c *Client) setState(state collectionState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.state = state
+}
+
+// Cleanup drops the frames of every embedding model, including the collection
+// of earlier versions, and swaps in an empty collection for the client's model
+// when its dimension is known. It also resolves a dimension mismatch reported
+// by New.
 func (c *Client) Cleanup(ctx context.Context) error {
-	err := c.Client.DeleteCollection(ctx, collectionName)
+	aliases, err := c.ListAliases(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list aliases: %w", err)
+	}
+
+	var ops []*qdrant.AliasOperations
+	for _, a := range aliases {
+		if isFrameCollection(a.GetAliasName()) {
+			ops = append(ops, qdrant.NewAliasDelete(a.GetAliasName()))
+		}
+	}
+
+	var name string
+	if c.dimension > 0 {
+		name = newCollectionName(c.collection)
+		if err := c.createCollection(ctx, name); err != nil {
+			return err
+		}
+		ops = append(ops, qdrant.NewAliasCreate(c.collection, name))
+	}
+
+	if len(ops) > 0 {
+		if err := c.UpdateAliases(ctx, ops); err != nil {
+			return fmt.Errorf("failed to update aliases: %w", err)
+		}
+	}
+
+	c.setState(collectionState{keywords: true, missing: name == ""})
+
+	collections, err := c.ListCollections(ctx)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to list collections: %w", err)
 	}
 
-	return c.createCollection(ctx, collectionName)
+	for _, collection := range collections {
+		if collection == name || !isFrameCollection(collection) {
+			continue
+		}
+
+		if err := c.Client.DeleteCollection(ctx, collection); err != nil {
+			return fmt.Errorf("failed to delete collection %q: %w", collection, err)
+		}
+	}
+
+	return nil
 }
 
-func (c *Client) Search(ctx context.Context, embedding []float32, limit uint64) ([]SearchResult, error) {
-	if len(embedding) != collectionDimensionality {
-		return nil, fmt.Errorf("embedding dimensions must be %d, got %d", collectionDimensionality, len(embedding))
+func (c *Client) Search(ctx context.Context, vector string, embedding []float32, limit uint64, filter store.Filter) ([]store.SearchResult, error) {
+	state := c.currentState()
+	if state.stale != nil {
+		return nil, state.stale
+	}
+
+	dimension := c.dimension
+	if vector == store.VectorImage {
+		if c.imageDimension == 0 {
+			return nil, fmt.Errorf("image embeddings are not enabled")
+		} else if state.images != nil {
+			return nil, state.images
+		}
+		dimension = c.imageDimension
+	}
+	if len(embedding) != dimension {
+		return nil, fmt.Errorf("embedding dimensions must be %d, got %d", dimension, len(embedding))
 	}
 	if limit < 1 {
 		return nil, fmt.Errorf("limit must be positive, got %d", limit)
 	}
 
-	rep, err := c.Query(ctx, &qdrant.QueryPoints{
-		CollectionName: collectionName,
+	query := &qdrant.QueryPoints{
+		CollectionName: c.collection,
 		Query:          qdrant.NewQuery(embedding...),
+		Using:          qdrant.PtrOf(vector),
+		Filter:         searchFilter(filter),
+		Limit:          &limit,
+		WithPayload:    qdrant.NewWithPayload(true),
+	}
+	if filter.MinScore > 0 {
+		query.ScoreThreshold = qdrant.PtrOf(filter.MinScore)
+	}
+
+	return c.search(ctx, query)
+}
+
+// SearchKeywords scores frames by their sparse term vectors, which Qdrant
+// weights by inverse document frequency.
+func (c *Client) SearchKeywords(ctx context.Context, query string, limit uint64, filter store.Filter) ([]store.SearchResult, error) {
+	state := c.currentState()
+	if state.stale != nil {
+		return nil, state.stale
+	}
+	if !state.keywords {
+		return nil, fmt.Errorf("%w in collection %q; run reindex --from-model %s to add it", store.ErrNoKeywordIndex, c.collection, c.model)
+	}
+	if limit < 1 {
+		return nil, fmt.Errorf("limit must be positive, got %d", limit)
+	}
+
+	terms := store.QueryTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	values := make([]float32, len(terms))
+	for i := range values {
+		values[i] = 1
+	}
+
+	return c.search(ctx, &qdrant.QueryPoints{
+		CollectionName: c.collection,
+		Query:          qdrant.NewQuerySparse(terms, values),
+		Using:          qdrant.PtrOf(store.VectorKeywords),
+		Filter:         searchFilter(filter),
 		Limit:          &limit,
 		WithPayload:    qdrant.NewWithPayload(true),
 	})
+}
+
+func (c *Client) search(ctx context.Context, query *qdrant.QueryPoints) ([]store.SearchResult, error) {
+	rep, err := c.Query(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query points: %w", err)
 	}
 
-	res := make([]SearchResult, 0, len(rep))
+	var videoIDs []string
+	for _, pt := range rep {
+		if id := pt.GetPayload()["video_id"].GetStringValue(); id != "" && !slices.Contains(videoIDs, id) {
+			videoIDs = append(videoIDs, id)
+		}
+	}
+
+	catalog, err := c.catalog(ctx, videoIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]store.SearchResult, 0, len(rep))
 	for _, pt := range rep {
 		payload := pt.GetPayload()
 
-		res = append(res, SearchResult{
-			Url:         payload["url"].GetStringValue(),
-			Timestamp:   payload["timestamp"].GetDoubleValue(),
-			Description: payload["description"].GetStringValue(),
-			Score:       pt.GetScore(),
+		res = append(res, store.SearchResult{
+			Kind:         payload["kind"].GetStringValue(),
+			Url:          payload["url"].GetStringValue(),
+			Timestamp:    payload["timestamp"].GetDoubleValue(),
+			EndTimestamp: payload["end_timestamp"].GetDoubleValue(),
+			Description:  payload["description"].GetStringValue(),
+			Score:        pt.GetScore(),
+			Metadata:     catalog[payload["video_id"].GetStringValue()],
+			Analysis:     parseAnalysis(payload),
 		})
 	}
 
 	return res, nil
 }
 
-func (c *Client) Store(ctx context.Context, url string, frame *video.Frame) error {
+func (c *Client) Store(ctx context.Context, src store.Source, frames []*video.Frame) error {
+	state := c.currentState()
+	if state.stale != nil {
+		return state.stale
+	}
+
+	points := make([]*qdrant.PointStruct, 0, len(frames)+1)
+	if src.Metadata != nil {
+		points = append(points, newCatalogPoint(src))
+	}
+	for _, frame := range frames {
+		if frame.Kind == "" {
+			return fmt.Errorf("frame at %s has no kind", frame.Timestamp)
+		}
+		points = append(points, c.newPoint(src, frame, state.keywords, state.images == nil))
+	}
+
 	_, err := c.Upsert(ctx, &qdrant.UpsertPoints{
-		CollectionName: collectionName,
-		Points: []*qdrant.PointStruct{{
-			Id:      qdrant.NewIDUUID(uuid.NewString()),
-			Vectors: qdrant.NewVectors(frame.Embedding...),
-			Payload: qdrant.NewValueMap(map[string]any{
-				"url":         url,
-				"timestamp":   frame.Timestamp.Seconds(),
-				"description": frame.Description,
-			}),
-		}},
+		CollectionName: c.collection,
+		Points:         points,
 	})
 
 	return err
 }
 
-func (c *Client) createCollection(ctx context.Context, collectionName string) error {
-	err := c.Client.CreateCollection(ctx, &qdrant.CreateCollection{
-		CollectionName: collectionName,
-		VectorsConfig: qdrant.NewVectorsConfig(&qdrant.VectorParams{
-			Size:     collectionDimensionality,
-			Distance: qdrant.Distance_Cosine,
-		}),
+func (c *Client) IsIndexed(ctx context.Context, field, value, samplingModel, embeddingModel string) (bool, error) {
+	if state := c.currentState(); state.stale != nil {
+		return false, state.stale
+	} else if state.missing {
+		return false, nil
+	}
+
+	filter := &qdrant.Filter{
+		Must: []*qdrant.Condition{
+			qdrant.NewMatch(field, value),
+			qdrant.NewMatch("sampling_model", samplingModel),
+			qdrant.NewMatch("embedding_model", embeddingModel),
+		},
+		MustNot: []*qdrant.Condition{
+			qdrant.NewMatch("kind", video.KindSpeech),
+		},
+	}
+
+	pts, err := c.Scroll(ctx, &qdrant.ScrollPoints{
+		CollectionName: c.collection,
+		Filter:         filter,
+		Limit:          qdrant.PtrOf(uint32(1)),
+		WithPayload:    qdrant.NewWithPayloadInclude("frames_total", "frames_stored"),
 	})
+	if err != nil {
+		return false, fmt.Errorf("failed to scroll points: %w", err)
+	} else if len(pts) == 0 {
+		return false, nil
+	}
 
+	count, err := c.Count(ctx, &qdrant.CountPoints{
+		CollectionName: c.collection,
+		Filter:         filter,
+		Exact:          qdrant.PtrOf(true),
+	})
 	if err != nil {
-		return err
+		return false, fmt.Errorf("failed to count points: %w", err)
 	}
 
-	return nil
+	payload := pts[0].GetPayload()
+	total := cmp.Or(payload["frames_stored"].GetIntegerValue(), payload["frames_total"].GetIntegerValue())
+
+	return count >= uint64(total), nil
+}
+
+func (c *Client) MarkIndexed(ctx context.Context, src store.Source, frames int) error {
+	if state := c.currentState(); state.stale != nil {
+		return state.stale
+	}
+
+	_, err := c.SetPayload(ctx, &qdrant.SetPayloadPoints{
+		CollectionName: c.collection,
+		Wait:           qdrant.PtrOf(true),
+		Payload:        qdrant.NewValueMap(map[string]any{"frames_stored": frames}),
+		PointsSelector: qdrant.NewPointsSelectorFilter(&qdrant.Filter{
+			Must: []*qdrant.Condition{
+				qdrant.NewMatch("video_id", src.VideoID),
+				qdrant.NewMatch("sampling_model", src.SamplingModel),
+				qdrant.NewMatch("embedding_model", src.EmbeddingModel),
+			},
+			MustNot: []*qdrant.Condition{
+				qdrant.NewMatch("kind", video.KindSpeech),
+			},
+		}),
+	})
+
+	return err
+}
+
+func (c *Client) DeleteFrames(ctx context.Context, videoID, samplingModel, embeddingModel string) error {
+	_, err := c.Client.Delete(ctx, &qdrant.DeletePoints{
+		CollectionName: c.collection,
+		Wait:           qdrant.PtrOf(true),
+		Points: qdrant.NewPointsSelectorFilter(&qdrant.Filter{
+			Must: []*qdrant.Condition{
+				qdrant.NewMatch("video_id", videoID),
+				qdrant.NewMatch("sampling_model", samplingModel),
+				qdrant.NewMatch("embedding_model", embeddingModel),
+			},
+		}),
+	})
+
+	return err
+}
+
+func (c *Client) Relocate(ctx context.Context, videoID, url string) error {
+	_, err := c.SetPayload(ctx, &qdrant.SetPayloadPoints{
+		CollectionName: c.collection,
+		Wait:           qdrant.PtrOf(true),
+		Payload:        qdrant.NewValueMap(map[string]any{"url": url}),
+		PointsSelector: qdrant.NewPointsSelectorFilter(&qdrant.Filter{
+			Must:    []*qdrant.Condition{qdrant.NewMatch("video_id", videoID)},
+			MustNot: []*qdrant.Condition{qdrant.NewMatch("url", url)},
+		}),
+	})
+
+	return err
+}
+
+// newPoint builds the point of a frame. The image vector is only set when the
+// target collection has one and the frame was embedded with it; speech
+// segments and frames reindexed from a collection without one have none. The
+// keyword vector is set unless the target collection predates keyword search.
+func (c *Client) newPoint(src store.Source, frame *video.Frame, keywords, images bool) *qdrant.PointStruct {
+	vectors := map[string]*qdrant.Vector{
+		store.VectorDescription: qdrant.NewVector(frame.Embedding...),
+	}
+	if images && c.imageDimension > 0 && len(frame.ImageEmbedding) == c.imageDimension {
+		vectors[store.VectorImage] = qdrant.NewVector(frame.ImageEmbedding...)
+	}
+	if indices, values := store.SparseVector(frame.Description); keywords && len(indices) > 0 {
+		vectors[store.VectorKeywords] = qdrant.NewVectorSparse(indices, values)
+	}
+
+	payload := map[string]any{
+		"kind":            frame.Kind,
+		"url":             src.URL,
+		"video_id":        src.VideoID,
+		"sampling_model":  src.SamplingModel,
+		"embedding_model": src.EmbeddingModel,
+		"channel":         src.Channel,
+		"tags":            anySlice(src.Tags),
+		"prompt_template": src.PromptTemplate,
+		"prompt_hash":     src.PromptHash,
+		"frames_total":    src.FramesTotal,
+		"indexed_at":      src.IndexedAt.UTC().Format(time.RFC3339),
+		"timestamp":       frame.Timestamp.Seconds(),
+		"end_timestamp":   frame.End.Seconds(),
+		"description":     frame.Description,
+	}
+	if src.FramesStored > 0 {
+		payload["frames_stored"] = src.FramesStored
+	}
+	analysisPayload(payload, frame.Analysis)
+
+	return &qdrant.PointStruct{
+		Id:      qdrant.NewIDUUID(store.PointID(src, frame).String()),
+		Vectors: qdrant.NewVectorsMap(vectors),
+		Payload: qdrant.NewValueMap(payload),
+	}
+}
+
+// searchFilter translates a store filter into Qdrant payload conditions.
+func searchFilter(filter store.Filter) *qdrant.Filter {
+	var must []*qdrant.Condition
+
+	if len(filter.Kinds) > 0 {
+		must = append(must, qdrant.NewMatchKeywords("kind", filter.Kinds...))
+	}
+	if len(filter.Videos) > 0 {
+		must = append(must, qdrant.NewFilterAsCondition(&qdrant.Filter{
+			Should: []*qdrant.Condition{
+				qdrant.NewMatchKeywords("video_id", filter.Videos...),
+				qdrant.NewMatchKeywords("url", filter.Videos...),
+			},
+		}))
+	}
+	if filter.From > 0 {
+		must = append(must, qdrant.NewRange("end_timestamp", &qdrant.Range{Gte: qdrant.PtrOf(filter.From)}))
+	}
+	if filter.To > 0 {
+		must = append(must, qdrant.NewRange("timestamp", &qdrant.Range{Lte: qdrant.PtrOf(filter.To)}))
+	}
+	if !filter.IndexedAfter.IsZero() || !filter.IndexedBefore.IsZero() {
+		indexed := &qdrant.DatetimeRange{}
+		if !filter.IndexedAfter.IsZero() {
+			indexed.Gte = timestamppb.New(filter.IndexedAfter)
+		}
+		if !filter.IndexedBefore.IsZero() {
+			indexed.Lte = timestamppb.New(filter.IndexedBefore)
+		}
+		must = append(must, qdrant.NewDatetimeRange("indexed_at", indexed))
+	}
+	if len(filter.Channels) > 0 {
+		must = append(must, qdrant.NewMatchKeywords("channel", filter.Channels...))
+	}
+	if len(filter.Tags) > 0 {
+		must = append(must, qdrant.NewMatchKeywords("tags", filter.Tags...))
+	}
+	must = append(must, analysisConditions(filter)...)
+
+	if len(must) == 0 {
+		return nil
+	}
+
+	return &qdrant.Filter{Must: must}
+}
+
+// anySlice converts values for qdrant.NewValueMap, which only accepts
+// untyped lists.
+func anySlice[T any](values []T) []any {
+	res := make([]any, len(values))
+	for i, v := range values {
+		res[i] = v
+	}
+
+	return res
 }
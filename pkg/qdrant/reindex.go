@@ -0,0 +1,358 @@
+package qdrant
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/store"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/video"
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// Reindex copies the frames of fromModel's collection into a staging
+// collection, re-embedding their descriptions, and then points the alias of
+// the client's model at it. Image vectors cannot be recomputed without the
+// frames, so they are carried over when their size still fits. An
+// interrupted run resumes in the staging collection it left behind by
+// skipping the frames already copied. Frames already indexed with the
+// client's model are carried over before the switch.
+func (c *Client) Reindex(ctx context.Context, fromModel string, embed store.EmbedFunc, progress func(done, total int)) error {
+	source, err := c.reindexSource(ctx, fromModel)
+	if err != nil {
+		return err
+	}
+
+	old, err := c.aliasTarget(ctx, c.collection)
+	if err != nil {
+		return err
+	}
+
+	staging, exists, err := c.stagingCollection(ctx, fromModel, old)
+	if err != nil {
+		return err
+	} else if !exists {
+		if err := c.createCollection(ctx, staging); err != nil {
+			return fmt.Errorf("failed to create staging collection: %w", err)
+		}
+	}
+
+	total, err := c.Count(ctx, &qdrant.CountPoints{
+		CollectionName: source,
+		Exact:          qdrant.PtrOf(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to count points: %w", err)
+	}
+
+	// the collection of earlier versions has a single unnamed vector
+	vectors := qdrant.NewWithVectors(false)
+	if source != collectionPrefix {
+		sizes, err := c.vectorSizes(ctx, source)
+		if err != nil {
+			return err
+		} else if _, ok := sizes[store.VectorImage]; ok {
+			vectors = qdrant.NewWithVectorsInclude(store.VectorImage)
+		}
+	}
+
+	done := 0
+	progress(done, int(total))
+
+	var offset *qdrant.PointId
+	for {
+		rep, err := c.GetPointsClient().Scroll(ctx, &qdrant.ScrollPoints{
+			CollectionName: source,
+			Offset:         offset,
+			Limit:          qdrant.PtrOf(uint32(scrollPageSize)),
+			WithPayload:    qdrant.NewWithPayload(true),
+			WithVectors:    vectors,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to scroll points: %w", err)
+		}
+
+		if err := c.reindexPage(ctx, staging, rep.GetResult(), embed); err != nil {
+			return err
+		}
+
+		done += len(rep.GetResult())
+		progress(done, int(total))
+
+		offset = rep.GetNextPageOffset()
+		if offset == nil {
+			break
+		}
+	}
+
+	if old != "" && old != source {
+		if err := c.copyTarget(ctx, old, staging, embed); err != nil {
+			return err
+		}
+	}
+
+	if err := c.pointAlias(ctx, c.collection, staging); err != nil {
+		return err
+	}
+	c.setState(collectionState{keywords: true})
+
+	if old != "" {
+		if err := c.Client.DeleteCollection(ctx, old); err != nil {
+			return fmt.Errorf("failed to delete old collection: %w", err)
+		}
+	}
+
+	// migrated frames are only read through the alias from now on
+	if source == collectionPrefix {
+		if err := c.Client.DeleteCollection(ctx, source); err != nil {
+			return fmt.Errorf("failed to delete migrated collection: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// reindexSource returns the collection holding the frames of fromModel, which
+// is the collection of earlier versions for LegacyModel.
+func (c *Client) reindexSource(ctx context.Context, fromModel string) (string, error) {
+	if fromModel == LegacyModel {
+		exists, err := c.CollectionExists(ctx, collectionPrefix)
+		if err != nil {
+			return "", fmt.Errorf("failed to check collection: %w", err)
+		} else if !exists {
+			return "", fmt.Errorf("no collection %q found from an earlier version", collectionPrefix)
+		}
+
+		return collectionPrefix, nil
+	}
+
+	source, err := c.aliasTarget(ctx, CollectionName(fromModel))
+	if err != nil {
+		return "", err
+	} else if source == "" {
+		return "", fmt.Errorf("no collection found for embedding model %q", fromModel)
+	}
+
+	return source, nil
+}
+
+// stagingCollection returns the collection a reindex from fromModel copies
+// into: the one an interrupted run left behind, or a new one named after the
+// two models. live is the collection currently behind the client's alias,
+// which is never reused.
+func (c *Client) stagingCollection(ctx context.Context, fromModel, live string) (string, bool, error) {
+	alias := c.collection + "-from-" + modelSlug(fromModel)
+
+	collections, err := c.ListCollections(ctx)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to list collections: %w", err)
+	}
+
+	for _, name := range collections {
+		created, ok := strings.CutPrefix(name, alias+"-")
+		if _, err := strconv.ParseInt(created, 10, 64); ok && err == nil && name != live {
+			return name, true, nil
+		}
+	}
+
+	return newCollectionName(alias), false, nil
+}
+
+// reindexPage embeds the points of one page that are not yet in staging, and
+// copies the catalog records among them.
+func (c *Client) reindexPage(ctx context.Context, staging string, page []*qdrant.RetrievedPoint, embed store.EmbedFunc) error {
+	var (
+		sources = make([]store.Source, 0, len(page))
+		frames  = make([]*video.Frame, 0, len(page))
+		ids     = make([]*qdrant.PointId, 0, len(page))
+		catalog []*qdrant.PointStruct
+	)
+	for _, pt := range page {
+		payload := pt.GetPayload()
+		if src, ok := catalogSource(payload); ok {
+			catalog = append(catalog, newCatalogPoint(src))
+			continue
+		}
+		if payload["description"].GetStringValue() == "" {
+			continue
+		}
+
+		src, frame := c.sourceFrame(payload)
+		frame.ImageEmbedding = vectorData(pt.GetVectors().GetVectors().GetVectors()[store.VectorImage])
+		frames = append(frames, frame)
+		sources = append(sources, src)
+		ids = append(ids, qdrant.NewIDUUID(store.PointID(src, frame).String()))
+	}
+
+	if len(frames) == 0 {
+		return c.upsertStaged(ctx, staging, catalog)
+	}
+
+	existing, err := c.Client.Get(ctx, &qdrant.GetPoints{
+		CollectionName: staging,
+		Ids:            ids,
+		WithPayload:    qdrant.NewWithPayload(false),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get staged points: %w", err)
+	}
+
+	copied := map[string]bool{}
+	for _, pt := range existing {
+		copied[pt.GetId().GetUuid()] = true
+	}
+
+	missing := catalog
+	for i, frame := range frames {
+		if copied[ids[i].GetUuid()] {
+			continue
+		}
+
+		embedding, err := embed(ctx, frame.Description)
+		if err != nil {
+			return fmt.Errorf("failed to get embedding: %w", err)
+		} else if len(embedding) != c.dimension {
+			return fmt.Errorf("embedding dimensions must be %d, got %d", c.dimension, len(embedding))
+		}
+
+		frame.Embedding = embedding
+		missing = append(missing, c.newPoint(sources[i], frame, true, true))
+	}
+
+	return c.upsertStaged(ctx, staging, missing)
+}
+
+func (c *Client) upsertStaged(ctx context.Context, staging string, points []*qdrant.PointStruct) error {
+	if len(points) == 0 {
+		return nil
+	}
+
+	_, err := c.Upsert(ctx, &qdrant.UpsertPoints{
+		CollectionName: staging,
+		Wait:           qdrant.PtrOf(true),
+		Points:         points,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upsert points: %w", err)
+	}
+
+	return nil
+}
+
+// copyTarget carries the frames already indexed with the client's model over
+// into staging, replacing their re-embedded copies, so they survive the alias
+// switch. Their embeddings are kept unless they no longer fit the collection.
+func (c *Client) copyTarget(ctx context.Context, target, staging string, embed store.EmbedFunc) error {
+	sizes, err := c.vectorSizes(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	names := []string{store.VectorDescription}
+	if _, ok := sizes[store.VectorImage]; ok {
+		names = append(names, store.VectorImage)
+	}
+
+	var offset *qdrant.PointId
+	for {
+		rep, err := c.GetPointsClient().Scroll(ctx, &qdrant.ScrollPoints{
+			CollectionName: target,
+			Offset:         offset,
+			Limit:          qdrant.PtrOf(uint32(scrollPageSize)),
+			WithPayload:    qdrant.NewWithPayload(true),
+			WithVectors:    qdrant.NewWithVectorsInclude(names...),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to scroll points: %w", err)
+		}
+
+		var points []*qdrant.PointStruct
+		for _, pt := range rep.GetResult() {
+			payload := pt.GetPayload()
+			if src, ok := catalogSource(payload); ok {
+				points = append(points, newCatalogPoint(src))
+				continue
+			} else if payload["description"].GetStringValue() == "" {
+				continue
+			}
+
+			vectors := pt.GetVectors().GetVectors().GetVectors()
+			src, frame := c.sourceFrame(payload)
+			frame.Embedding = vectorData(vectors[store.VectorDescription])
+			frame.ImageEmbedding = vectorData(vectors[store.VectorImage])
+
+			if len(frame.Embedding) != c.dimension {
+				frame.Embedding, err = embed(ctx, frame.Description)
+				if err != nil {
+					return fmt.Errorf("failed to get embedding: %w", err)
+				} else if len(frame.Embedding) != c.dimension {
+					return fmt.Errorf("embedding dimensions must be %d, got %d", c.dimension, len(frame.Embedding))
+				}
+			}
+
+			points = append(points, c.newPoint(src, frame, true, true))
+		}
+
+		if err := c.upsertStaged(ctx, staging, points); err != nil {
+			return err
+		}
+
+		offset = rep.GetNextPageOffset()
+		if offset == nil {
+			return nil
+		}
+	}
+}
+
+// sourceFrame rebuilds the source and frame of a stored point as they would
+// be stored with the client's embedding model.
+func (c *Client) sourceFrame(payload map[string]*qdrant.Value) (store.Source, *video.Frame) {
+	indexedAt, _ := time.Parse(time.RFC3339, payload["indexed_at"].GetStringValue())
+
+	src := store.Source{
+		// frames stored before videos were identified by hash have no ID
+		VideoID:        cmp.Or(payload["video_id"].GetStringValue(), payload["url"].GetStringValue()),
+		URL:            payload["url"].GetStringValue(),
+		SamplingModel:  payload["sampling_model"].GetStringValue(),
+		EmbeddingModel: c.model,
+		Channel:        payload["channel"].GetStringValue(),
+		Tags:           stringList(payload["tags"]),
+		PromptTemplate: payload["prompt_template"].GetStringValue(),
+		PromptHash:     payload["prompt_hash"].GetStringValue(),
+		FramesTotal:    int(payload["frames_total"].GetIntegerValue()),
+		FramesStored:   int(payload["frames_stored"].GetIntegerValue()),
+		IndexedAt:      indexedAt,
+	}
+
+	frame := &video.Frame{
+		// the frames of earlier versions are all described by the vision model
+		Kind:        cmp.Or(payload["kind"].GetStringValue(), video.KindVisual),
+		Timestamp:   store.Seconds(payload["timestamp"].GetDoubleValue()),
+		End:         store.Seconds(payload["end_timestamp"].GetDoubleValue()),
+		Description: payload["description"].GetStringValue(),
+		Analysis:    parseAnalysis(payload),
+	}
+
+	return src, frame
+}
+
+func stringList(value *qdrant.Value) []string {
+	var res []string
+	for _, v := range value.GetListValue().GetValues() {
+		res = append(res, v.GetStringValue())
+	}
+
+	return res
+}
+
+// vectorData returns the values of a dense vector, or nil if it is missing.
+func vectorData(v *qdrant.VectorOutput) []float32 {
+	if dense := v.GetDense(); dense != nil {
+		return dense.GetData()
+	}
+
+	return v.GetData()
+}
@@ -0,0 +1,149 @@
+package qdrant
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/store"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/video"
+	"github.com/qdrant/go-client/qdrant"
+)
+
+const scrollPageSize = 256
+
+func (c *Client) List(ctx context.Context) ([]store.VideoSummary, error) {
+	return c.videos(ctx, nil)
+}
+
+func (c *Client) Get(ctx context.Context, idOrURL string) (*store.VideoSummary, error) {
+	res, err := c.videos(ctx, videoFilter(idOrURL))
+	if err != nil {
+		return nil, err
+	} else if len(res) == 0 {
+		return nil, fmt.Errorf("%w: %s", store.ErrVideoNotFound, idOrURL)
+	}
+
+	return &res[0], nil
+}
+
+func (c *Client) Delete(ctx context.Context, idOrURL string) (uint64, error) {
+	if c.currentState().missing {
+		return 0, fmt.Errorf("%w: %s", store.ErrVideoNotFound, idOrURL)
+	}
+
+	filter := videoFilter(idOrURL)
+
+	count, err := c.Count(ctx, &qdrant.CountPoints{
+		CollectionName: c.collection,
+		Filter: &qdrant.Filter{
+			Should:  filter.Should,
+			MustNot: []*qdrant.Condition{qdrant.NewMatch("kind", catalogKind)},
+		},
+		Exact: qdrant.PtrOf(true),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count points: %w", err)
+	} else if count == 0 {
+		return 0, fmt.Errorf("%w: %s", store.ErrVideoNotFound, idOrURL)
+	}
+
+	_, err = c.Client.Delete(ctx, &qdrant.DeletePoints{
+		CollectionName: c.collection,
+		Wait:           qdrant.PtrOf(true),
+		Points:         qdrant.NewPointsSelectorFilter(filter),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete points: %w", err)
+	}
+
+	return count, nil
+}
+
+func (c *Client) videos(ctx context.Context, filter *qdrant.Filter) ([]store.VideoSummary, error) {
+	if c.currentState().missing {
+		return nil, nil
+	}
+
+	summaries := map[string]*store.VideoSummary{}
+	catalog := map[string]*video.Metadata{}
+
+	var offset *qdrant.PointId
+	for {
+		rep, err := c.GetPointsClient().Scroll(ctx, &qdrant.ScrollPoints{
+			CollectionName: c.collection,
+			Filter:         filter,
+			Offset:         offset,
+			Limit:          qdrant.PtrOf(uint32(scrollPageSize)),
+			WithPayload:    qdrant.NewWithPayloadInclude("kind", "url", "video_id", "sampling_model", "embedding_model", "indexed_at", "video"),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scroll points: %w", err)
+		}
+
+		for _, pt := range rep.GetResult() {
+			payload := pt.GetPayload()
+			if payload["kind"].GetStringValue() == catalogKind {
+				catalog[payload["video_id"].GetStringValue()] = parseMetadata(payload["video"])
+				continue
+			}
+
+			url := payload["url"].GetStringValue()
+			id := payload["video_id"].GetStringValue()
+			if id == "" {
+				// frames stored before videos were identified by hash
+				id = url
+			}
+
+			summary, ok := summaries[id]
+			if !ok {
+				summary = &store.VideoSummary{ID: id}
+				summaries[id] = summary
+			}
+
+			summary.Frames++
+			summary.URLs = appendUnique(summary.URLs, url)
+			summary.SamplingModels = appendUnique(summary.SamplingModels, payload["sampling_model"].GetStringValue())
+			summary.EmbeddingModels = appendUnique(summary.EmbeddingModels, payload["embedding_model"].GetStringValue())
+
+			if t, err := time.Parse(time.RFC3339, payload["indexed_at"].GetStringValue()); err == nil && t.After(summary.IndexedAt) {
+				summary.IndexedAt = t
+			}
+		}
+
+		offset = rep.GetNextPageOffset()
+		if offset == nil {
+			break
+		}
+	}
+
+	res := make([]store.VideoSummary, 0, len(summaries))
+	for id, summary := range summaries {
+		summary.Metadata = catalog[id]
+		res = append(res, *summary)
+	}
+
+	slices.SortFunc(res, func(a, b store.VideoSummary) int {
+		return b.IndexedAt.Compare(a.IndexedAt)
+	})
+
+	return res, nil
+}
+
+func videoFilter(idOrURL string) *qdrant.Filter {
+	return &qdrant.Filter{
+		Should: []*qdrant.Condition{
+			qdrant.NewMatch("video_id", idOrURL),
+			qdrant.NewMatch("url", idOrURL),
+		},
+	}
+}
+
+func appendUnique(values []string, value string) []string {
+	if value == "" || slices.Contains(values, value) {
+		return values
+	}
+
+	return append(values, value)
+}
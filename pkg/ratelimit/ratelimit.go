@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter spaces out calls so they happen at most at a fixed rate.
+type Limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = map[string]*Limiter{}
+)
+
+func New(perSecond float64) *Limiter {
+	return &Limiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// Wait blocks until the next call is allowed or ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Wait blocks until a request to host is allowed under the limit shared by
+// every caller for that host. A non-positive rate disables limiting.
+func Wait(ctx context.Context, host string, perSecond float64) error {
+	if perSecond <= 0 {
+		return nil
+	}
+
+	limitersMu.Lock()
+	l, ok := limiters[host]
+	if !ok {
+		l = New(perSecond)
+		limiters[host] = l
+	}
+	limitersMu.Unlock()
+
+	return l.Wait(ctx)
+}
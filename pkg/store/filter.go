@@ -0,0 +1,87 @@
+package store
+
+import (
+	"slices"
+	"time"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/video"
+)
+
+// Filter restricts a search. Zero values leave a field unrestricted.
+type Filter struct {
+	// Kinds lists the kinds of frames to search, such as video.KindVisual
+	// and video.KindSpeech.
+	Kinds []string `json:"kinds,omitempty"`
+	// Videos lists video IDs or URLs to search in.
+	Videos []string `json:"videos,omitempty"`
+	// From and To bound the window within each video, in seconds. Frames
+	// overlapping the window match.
+	From float64 `json:"from,omitempty"`
+	To   float64 `json:"to,omitempty"`
+	// IndexedAfter and IndexedBefore bound when the frames were indexed.
+	IndexedAfter  time.Time `json:"indexed_after"`
+	IndexedBefore time.Time `json:"indexed_before"`
+	// Channels and Tags match frames having any of the given values.
+	Channels []string `json:"channels,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	// Objects match frames showing every one of the objects, while
+	// SceneTypes and Colors match frames having any of the values. Like
+	// HasText and the people bounds, they only match frames processed in
+	// structured mode.
+	Objects    []string `json:"objects,omitempty"`
+	SceneTypes []string `json:"scene_types,omitempty"`
+	Colors     []string `json:"colors,omitempty"`
+	// HasText matches frames with or without on-screen text.
+	HasText *bool `json:"has_text,omitempty"`
+	// MinPeople and MaxPeople bound the number of people in frame.
+	MinPeople *int `json:"min_people,omitempty"`
+	MaxPeople *int `json:"max_people,omitempty"`
+	// MinScore drops results of vector searches whose similarity is below
+	// it. It applies before rankings are fused, so fused scores may be lower,
+	// and keyword results are kept whatever their score.
+	MinScore float32 `json:"min_score,omitempty"`
+}
+
+// Labels returns the objects, scene types and colors of the filter cleaned up
+// like the labels of analyses, so they match whatever their case.
+func (f Filter) Labels() (objects, sceneTypes, colors []string) {
+	return video.Labels(f.Objects), video.Labels(f.SceneTypes), video.Labels(f.Colors)
+}
+
+// MatchesAnalysis reports whether a frame with the given structured analysis
+// passes the filter.
+func (f Filter) MatchesAnalysis(a *video.Analysis) bool {
+	if !f.structured() {
+		return true
+	} else if a == nil {
+		return false
+	}
+
+	objects, sceneTypes, colors := f.Labels()
+	switch {
+	case slices.ContainsFunc(objects, func(o string) bool { return !slices.Contains(a.Objects, o) }):
+		return false
+	case len(sceneTypes) > 0 && !slices.Contains(sceneTypes, a.SceneType):
+		return false
+	case len(colors) > 0 && !slices.ContainsFunc(colors, func(c string) bool { return slices.Contains(a.Colors, c) }):
+		return false
+	case f.HasText != nil && *f.HasText != (a.OnScreenText != ""):
+		return false
+	case f.MinPeople != nil && a.PeopleCount < *f.MinPeople:
+		return false
+	case f.MaxPeople != nil && a.PeopleCount > *f.MaxPeople:
+		return false
+	}
+
+	return true
+}
+
+func (f Filter) structured() bool {
+	return len(f.Objects) > 0 || len(f.SceneTypes) > 0 || len(f.Colors) > 0 ||
+		f.HasText != nil || f.MinPeople != nil || f.MaxPeople != nil
+}
+
+// MatchesKind reports whether a frame of the given kind passes the filter.
+func (f Filter) MatchesKind(kind string) bool {
+	return len(f.Kinds) == 0 || slices.Contains(f.Kinds, kind)
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/video"
+)
+
+func TestMatchesAnalysis(t *testing.T) {
+	analysis := &video.Analysis{
+		Objects:     []string{"car", "tree"},
+		PeopleCount: 2,
+		SceneType:   "outdoor",
+		Colors:      []string{"red"},
+	}
+	two := 2
+
+	tests := []struct {
+		name       string
+		filter     Filter
+		noAnalysis bool
+		want       bool
+	}{
+		{name: "no structured fields", filter: Filter{}, want: true},
+		{name: "no structured fields without analysis", filter: Filter{}, noAnalysis: true, want: true},
+		{name: "every object", filter: Filter{Objects: []string{"car", "tree"}}, want: true},
+		{name: "missing object", filter: Filter{Objects: []string{"car", "boat"}}},
+		{name: "label case", filter: Filter{Objects: []string{" Car "}, SceneTypes: []string{"OUTDOOR"}, Colors: []string{"Red"}}, want: true},
+		{name: "any color", filter: Filter{Colors: []string{"blue", "red"}}, want: true},
+		{name: "other scene", filter: Filter{SceneTypes: []string{"indoor"}}},
+		{name: "people bounds", filter: Filter{MinPeople: &two, MaxPeople: &two}, want: true},
+		{name: "no analysis", filter: Filter{Objects: []string{"car"}}, noAnalysis: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := analysis
+			if tt.noAnalysis {
+				a = nil
+			}
+
+			if got := tt.filter.MatchesAnalysis(a); got != tt.want {
+				t.Errorf("MatchesAnalysis = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"errors"
+	"hash/fnv"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// bm25K1 controls how quickly repeated terms stop adding to a BM25 score.
+// Descriptions are of similar length, so BM25's length normalisation is left
+// out and a frame's term weights do not depend on the rest of the collection.
+const bm25K1 = 1.2
+
+// ErrNoKeywordIndex is returned by SearchKeywords when the stored frames were
+// indexed before keyword search existed.
+var ErrNoKeywordIndex = errors.New("no keyword index")
+
+var termPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// Terms splits text into lowercase words.
+func Terms(text string) []string {
+	return termPattern.FindAllString(strings.ToLower(text), -1)
+}
+
+// TermID hashes a term to its index in a sparse vector.
+func TermID(term string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(term))
+
+	return h.Sum32()
+}
+
+// TermWeights returns the saturated BM25 term frequency of every term of
+// text by term ID. The inverse document frequency is applied at search time.
+func TermWeights(text string) map[uint32]float32 {
+	counts := map[uint32]float32{}
+	for _, term := range Terms(text) {
+		counts[TermID(term)]++
+	}
+
+	for id, tf := range counts {
+		counts[id] = tf * (bm25K1 + 1) / (tf + bm25K1)
+	}
+
+	return counts
+}
+
+// QueryTerms returns the distinct term IDs of a keyword query.
+func QueryTerms(query string) []uint32 {
+	var ids []uint32
+	for _, term := range Terms(query) {
+		ids = append(ids, TermID(term))
+	}
+	slices.Sort(ids)
+
+	return slices.Compact(ids)
+}
+
+// SparseVector returns the term weights of text as sorted sparse vector
+// indices and values.
+func SparseVector(text string) ([]uint32, []float32) {
+	weights := TermWeights(text)
+
+	indices := make([]uint32, 0, len(weights))
+	for id := range weights {
+		indices = append(indices, id)
+	}
+	slices.Sort(indices)
+
+	values := make([]float32, len(indices))
+	for i, id := range indices {
+		values[i] = weights[id]
+	}
+
+	return indices, values
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"A red car, parked.", []string{"a", "red", "car", "parked"}},
+		{"Café au lait 2x", []string{"café", "au", "lait", "2x"}},
+		{"  -- ", nil},
+	}
+
+	for _, tt := range tests {
+		if got := Terms(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("Terms(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTermWeights(t *testing.T) {
+	weights := TermWeights("car car car boat")
+
+	car, boat := weights[TermID("car")], weights[TermID("boat")]
+	if boat != 1 {
+		t.Errorf("weight of a single term = %v, want 1", boat)
+	}
+	if car <= boat || car >= bm25K1+1 {
+		t.Errorf("weight of a repeated term = %v, want between %v and %v", car, boat, bm25K1+1)
+	}
+}
+
+func TestQueryTerms(t *testing.T) {
+	got := QueryTerms("Red car RED")
+
+	want := []uint32{TermID("red"), TermID("car")}
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Errorf("QueryTerms = %v, want %v", got, want)
+	}
+}
+
+func TestSparseVector(t *testing.T) {
+	indices, values := SparseVector("a red car a")
+
+	if !slices.IsSorted(indices) {
+		t.Errorf("indices %v are not sorted", indices)
+	}
+
+	weights := TermWeights("a red car a")
+	if len(indices) != len(weights) {
+		t.Fatalf("sparse vector has %d terms, want %d", len(indices), len(weights))
+	}
+	for i, id := range indices {
+		if values[i] != weights[id] {
+			t.Errorf("value of term %d = %v, want %v", id, values[i], weights[id])
+		}
+	}
+}
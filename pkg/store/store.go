@@ -0,0 +1,169 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/video"
+)
+
+// VectorStore persists frame embeddings and searches them by similarity.
+type VectorStore interface {
+	Store(ctx context.Context, src Source, frames []*video.Frame) error
+	// Search returns the frames whose vector of the given name is most
+	// similar to embedding.
+	Search(ctx context.Context, vector string, embedding []float32, limit uint64, filter Filter) ([]SearchResult, error)
+	// SearchKeywords returns the frames whose descriptions best match the
+	// words of query by BM25. Keyword scores are unbounded, so the filter's
+	// MinScore does not apply.
+	SearchKeywords(ctx context.Context, query string, limit uint64, filter Filter) ([]SearchResult, error)
+	// Facets counts the most common values of the structured fields among
+	// the frames matching filter, up to limit values per field.
+	Facets(ctx context.Context, filter Filter, limit int) (*Facets, error)
+	// IsIndexed reports whether every visual frame of the video whose payload
+	// field equals value has been stored for the given models. Frames that
+	// could not be described are not expected once the video is marked
+	// indexed.
+	IsIndexed(ctx context.Context, field, value, samplingModel, embeddingModel string) (bool, error)
+	// MarkIndexed records that processing src finished with frames visual
+	// frames stored.
+	MarkIndexed(ctx context.Context, src Source, frames int) error
+	// DeleteFrames removes every frame of a video indexed with the given models.
+	DeleteFrames(ctx context.Context, videoID, samplingModel, embeddingModel string) error
+	// Relocate points every frame of a video at url, so local videos stay
+	// reachable after they are moved. Like List, Get and Delete, it only
+	// covers the frames of the store's embedding model.
+	Relocate(ctx context.Context, videoID, url string) error
+	// List returns every video indexed with the store's embedding model, most
+	// recently indexed first.
+	List(ctx context.Context) ([]VideoSummary, error)
+	// Get returns the summary of a video by its ID or URL.
+	Get(ctx context.Context, idOrURL string) (*VideoSummary, error)
+	// Delete removes every frame of a video by its ID or URL and returns the
+	// number of frames deleted.
+	Delete(ctx context.Context, idOrURL string) (uint64, error)
+	// Cleanup removes every stored frame of every embedding model.
+	Cleanup(ctx context.Context) error
+	// Reindex re-embeds the descriptions stored for fromModel with the store's
+	// embedding model. The new embeddings only become searchable once every
+	// frame is done, and an interrupted run resumes where it left off.
+	// progress is called after every batch.
+	Reindex(ctx context.Context, fromModel string, embed EmbedFunc, progress func(done, total int)) error
+	// Close releases the connection or file the store holds.
+	Close() error
+}
+
+const (
+	// VectorDescription is the embedding of a frame's description.
+	VectorDescription = "description"
+	// VectorImage is the embedding of a frame's pixels by a multimodal
+	// model, in the same space as that model's text embeddings.
+	VectorImage = "image"
+	// VectorKeywords is the sparse BM25 term vector of a frame's description.
+	VectorKeywords = "keywords"
+)
+
+// Dimensions are the vector sizes a store is opened with. Image is zero when
+// image embeddings are disabled, and Description is zero when the sizes were
+// not probed; such a store can list, filter, delete and clean frames but not
+// store or search them.
+type Dimensions struct {
+	Description int
+	Image       int
+}
+
+// EmbedFunc turns a stored description into an embedding.
+type EmbedFunc func(ctx context.Context, text string) ([]float32, error)
+
+var ErrVideoNotFound = errors.New("video not found")
+
+// ErrNoImageIndex is returned by Search for image vectors when the stored
+// frames have none of the configured size until the store is reindexed.
+var ErrNoImageIndex = errors.New("no image index")
+
+type SearchResult struct {
+	Kind         string
+	Url          string
+	Timestamp    float64
+	EndTimestamp float64
+	Description  string
+	Score        float32
+	Metadata     *video.Metadata
+	Analysis     *video.Analysis
+}
+
+// FacetCount is the number of frames having a value.
+type FacetCount struct {
+	Value string `json:"value"`
+	Count uint64 `json:"count"`
+}
+
+// Facets are the value counts of the structured fields of frames, most
+// common first.
+type Facets struct {
+	Objects    []FacetCount `json:"objects"`
+	SceneTypes []FacetCount `json:"scene_types"`
+	Colors     []FacetCount `json:"colors"`
+}
+
+// Source identifies the video and models a set of frames was indexed with.
+type Source struct {
+	VideoID        string
+	URL            string
+	SamplingModel  string
+	EmbeddingModel string
+	// Channel and Tags label the video so searches can be filtered by them.
+	Channel string
+	Tags    []string
+	// Metadata is the catalog record of the video, if known. Stores keep one
+	// record per video rather than copying it into every frame.
+	Metadata *video.Metadata
+	// PromptTemplate and PromptHash identify the template the frames were
+	// described with. Speech segments have none.
+	PromptTemplate string
+	PromptHash     string
+	// FramesTotal is the number of frames extracted for the video, used to
+	// tell whether every frame made it into the store.
+	FramesTotal int
+	// FramesStored is the number of frames stored once the video was marked
+	// indexed, or zero until then.
+	FramesStored int
+	IndexedAt    time.Time
+}
+
+// VideoSummary aggregates the frames stored for one video.
+type VideoSummary struct {
+	ID              string    `json:"id"`
+	URLs            []string  `json:"urls"`
+	Frames          int       `json:"frames"`
+	SamplingModels  []string  `json:"sampling_models"`
+	EmbeddingModels []string  `json:"embedding_models"`
+	IndexedAt       time.Time `json:"indexed_at"`
+	// Metadata is the catalog record of the video.
+	Metadata *video.Metadata `json:"metadata,omitempty"`
+}
+
+var pointNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://github.com/mahyarmirrashed/llm-video-analyzer"))
+
+// PointID derives a stable ID for a frame, so storing the same frame of the
+// same video with the same models again overwrites the existing point.
+func PointID(src Source, frame *video.Frame) uuid.UUID {
+	name := fmt.Sprintf("%s|%d|%s|%s|%s", src.VideoID, frame.Timestamp.Milliseconds(), src.SamplingModel, src.EmbeddingModel, frame.Kind)
+	return uuid.NewSHA1(pointNamespace, []byte(name))
+}
+
+// CatalogID returns the ID of the catalog record of a video in stores that
+// keep it among the frames.
+func CatalogID(videoID string) uuid.UUID {
+	return uuid.NewSHA1(pointNamespace, []byte("catalog|"+videoID))
+}
+
+// Seconds converts a stored timestamp back into a duration, rounded to the
+// millisecond so point IDs derived from it are stable.
+func Seconds(s float64) time.Duration {
+	return time.Duration(math.Round(s*1000)) * time.Millisecond
+}
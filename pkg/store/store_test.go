@@ -0,0 +1,109 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/video"
+)
+
+func TestPointID(t *testing.T) {
+	src := Source{VideoID: "abc", SamplingModel: "llava", EmbeddingModel: "nomic"}
+	frame := &video.Frame{Kind: video.KindVisual, Timestamp: 1500 * time.Millisecond}
+
+	tests := []struct {
+		name  string
+		src   Source
+		frame *video.Frame
+		same  bool
+	}{
+		{
+			name:  "same frame",
+			src:   src,
+			frame: &video.Frame{Kind: video.KindVisual, Timestamp: 1500 * time.Millisecond},
+			same:  true,
+		},
+		{
+			name:  "stored timestamp round trip",
+			src:   src,
+			frame: &video.Frame{Kind: video.KindVisual, Timestamp: Seconds(1.5)},
+			same:  true,
+		},
+		{
+			name:  "sub-millisecond difference",
+			src:   src,
+			frame: &video.Frame{Kind: video.KindVisual, Timestamp: 1500*time.Millisecond + 300*time.Microsecond},
+			same:  true,
+		},
+		{
+			name: "url and description do not matter",
+			src: Source{
+				VideoID:        "abc",
+				URL:            "file:///videos/abc.mp4",
+				SamplingModel:  "llava",
+				EmbeddingModel: "nomic",
+			},
+			frame: &video.Frame{Kind: video.KindVisual, Timestamp: 1500 * time.Millisecond, Description: "a cat"},
+			same:  true,
+		},
+		{
+			name:  "other timestamp",
+			src:   src,
+			frame: &video.Frame{Kind: video.KindVisual, Timestamp: 2 * time.Second},
+		},
+		{
+			name:  "speech at the same time",
+			src:   src,
+			frame: &video.Frame{Kind: video.KindSpeech, Timestamp: 1500 * time.Millisecond},
+		},
+		{
+			name:  "segment at the same time",
+			src:   src,
+			frame: &video.Frame{Kind: video.KindSegment, Timestamp: 1500 * time.Millisecond},
+		},
+		{
+			name:  "other video",
+			src:   Source{VideoID: "def", SamplingModel: "llava", EmbeddingModel: "nomic"},
+			frame: frame,
+		},
+		{
+			name:  "other sampling model",
+			src:   Source{VideoID: "abc", SamplingModel: "moondream", EmbeddingModel: "nomic"},
+			frame: frame,
+		},
+		{
+			name:  "other embedding model",
+			src:   Source{VideoID: "abc", SamplingModel: "llava", EmbeddingModel: "mxbai"},
+			frame: frame,
+		},
+	}
+
+	want := PointID(src, frame)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PointID(tt.src, tt.frame)
+			if (got == want) != tt.same {
+				t.Errorf("PointID = %s, base ID %s, want same %v", got, want, tt.same)
+			}
+		})
+	}
+}
+
+func TestSeconds(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want time.Duration
+	}{
+		{0, 0},
+		{1.5, 1500 * time.Millisecond},
+		{0.0004, 0},
+		{0.0006, time.Millisecond},
+		{3723.25, time.Hour + 2*time.Minute + 3*time.Second + 250*time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		if got := Seconds(tt.in); got != tt.want {
+			t.Errorf("Seconds(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
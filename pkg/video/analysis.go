@@ -0,0 +1,105 @@
+package video
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SceneTypes are the scene types the vision model chooses from.
+var SceneTypes = []string{
+	"presentation",
+	"whiteboard",
+	"screencast",
+	"document",
+	"talking-head",
+	"indoor",
+	"outdoor",
+	"animation",
+	"other",
+}
+
+// Analysis is the structured description of a frame. Objects, SceneType and
+// Colors are lowercase so they can be filtered and faceted exactly.
+type Analysis struct {
+	Summary      string   `json:"summary"`
+	Objects      []string `json:"objects"`
+	PeopleCount  int      `json:"people_count"`
+	OnScreenText string   `json:"on_screen_text"`
+	SceneType    string   `json:"scene_type"`
+	Colors       []string `json:"colors"`
+}
+
+// analysisPrompt is appended to the frame prompt in structured mode.
+const analysisPrompt = "Answer in JSON: summarise the frame in a paragraph, list the visible objects, count the people, transcribe any on-screen text verbatim, pick the scene type and name the dominant colours."
+
+// AnalysisSchema is the JSON schema the vision model's answer must match.
+func AnalysisSchema() map[string]any {
+	list := map[string]any{
+		"type":  "array",
+		"items": map[string]any{"type": "string"},
+	}
+
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"summary":        map[string]any{"type": "string"},
+			"objects":        list,
+			"people_count":   map[string]any{"type": "integer", "minimum": 0},
+			"on_screen_text": map[string]any{"type": "string"},
+			"scene_type":     map[string]any{"type": "string", "enum": SceneTypes},
+			"colors":         list,
+		},
+		"required": []string{"summary", "objects", "people_count", "on_screen_text", "scene_type", "colors"},
+	}
+}
+
+// ParseAnalysis reads the vision model's answer and normalises its labels.
+func ParseAnalysis(data string) (*Analysis, error) {
+	var a Analysis
+	if err := json.Unmarshal([]byte(data), &a); err != nil {
+		return nil, fmt.Errorf("failed to parse frame analysis: %w", err)
+	}
+
+	a.Summary = strings.TrimSpace(a.Summary)
+	a.OnScreenText = strings.TrimSpace(a.OnScreenText)
+	a.Objects = Labels(a.Objects)
+	a.Colors = Labels(a.Colors)
+	a.PeopleCount = max(a.PeopleCount, 0)
+
+	a.SceneType = strings.ToLower(strings.TrimSpace(a.SceneType))
+	if !slices.Contains(SceneTypes, a.SceneType) {
+		a.SceneType = "other"
+	}
+
+	return &a, nil
+}
+
+// Description renders the analysis as the text that is embedded and shown,
+// so structured frames stay searchable by meaning.
+func (a *Analysis) Description() string {
+	parts := []string{a.Summary}
+	if len(a.Objects) > 0 {
+		parts = append(parts, "Objects: "+strings.Join(a.Objects, ", ")+".")
+	}
+	if a.OnScreenText != "" {
+		parts = append(parts, "On-screen text: "+a.OnScreenText)
+	}
+
+	return strings.Join(parts, "\n")
+}
+
+// Labels cleans up object and color labels the way analyses store them:
+// trimmed, lower case and without duplicates.
+func Labels(values []string) []string {
+	res := make([]string, 0, len(values))
+	for _, v := range values {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v != "" && !slices.Contains(res, v) {
+			res = append(res, v)
+		}
+	}
+
+	return res
+}
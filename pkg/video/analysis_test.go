@@ -0,0 +1,99 @@
+package video
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAnalysis(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    *Analysis
+		wantErr bool
+	}{
+		{
+			name: "complete",
+			data: `{"summary":"A talk.","objects":["podium","laptop"],"people_count":1,"on_screen_text":"Welcome","scene_type":"presentation","colors":["blue"]}`,
+			want: &Analysis{
+				Summary:      "A talk.",
+				Objects:      []string{"podium", "laptop"},
+				PeopleCount:  1,
+				OnScreenText: "Welcome",
+				SceneType:    "presentation",
+				Colors:       []string{"blue"},
+			},
+		},
+		{
+			name: "normalises labels",
+			data: `{"summary":"  A street. ","objects":[" Car","car","", "Tree "],"people_count":-2,"on_screen_text":" ","scene_type":" Outdoor ","colors":["Red","RED"]}`,
+			want: &Analysis{
+				Summary:     "A street.",
+				Objects:     []string{"car", "tree"},
+				PeopleCount: 0,
+				SceneType:   "outdoor",
+				Colors:      []string{"red"},
+			},
+		},
+		{
+			name: "unknown scene type",
+			data: `{"summary":"Space.","scene_type":"galaxy"}`,
+			want: &Analysis{
+				Summary:   "Space.",
+				Objects:   []string{},
+				SceneType: "other",
+				Colors:    []string{},
+			},
+		},
+		{
+			name:    "not json",
+			data:    "A frame showing a cat.",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAnalysis(tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("ParseAnalysis succeeded, want an error")
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("ParseAnalysis: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAnalysis = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalysisDescription(t *testing.T) {
+	tests := []struct {
+		name     string
+		analysis Analysis
+		want     string
+	}{
+		{
+			name:     "summary only",
+			analysis: Analysis{Summary: "A cat."},
+			want:     "A cat.",
+		},
+		{
+			name:     "objects and text",
+			analysis: Analysis{Summary: "A slide.", Objects: []string{"chart", "logo"}, OnScreenText: "Q3 results"},
+			want:     "A slide.\nObjects: chart, logo.\nOn-screen text: Q3 results",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.analysis.Description(); got != tt.want {
+				t.Errorf("Description = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
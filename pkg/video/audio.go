@@ -0,0 +1,64 @@
+package video
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AudioChunk is a piece of a video's soundtrack starting at Offset.
+type AudioChunk struct {
+	Path   string
+	Offset time.Duration
+}
+
+// ExtractAudio writes the soundtrack of the video as 16 kHz mono WAV files of
+// at most length each, the format speech-to-text models expect.
+func (v *Video) ExtractAudio(ctx context.Context, length time.Duration) ([]AudioChunk, error) {
+	if length <= 0 {
+		return nil, fmt.Errorf("audio chunk length must be positive, got %v", length)
+	}
+
+	dir := filepath.Join(v.WorkDir, "audio", v.ID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	v.AudioPath = dir
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-i", v.Path,
+		"-vn",
+		"-ac", "1",
+		"-ar", "16000",
+		"-f", "segment",
+		"-segment_time", strconv.FormatFloat(length.Seconds(), 'f', -1, 64),
+		filepath.Join(dir, "audio_%05d.wav"),
+	)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg error: %w: %s", err, lastLine(stderr.String()))
+	}
+
+	paths, _ := filepath.Glob(filepath.Join(dir, "audio_*.wav"))
+
+	chunks := make([]AudioChunk, len(paths))
+	for i, path := range paths {
+		chunks[i] = AudioChunk{Path: path, Offset: time.Duration(i) * length}
+	}
+
+	return chunks, nil
+}
+
+func lastLine(s string) string {
+	s = strings.TrimSpace(s)
+
+	return s[strings.LastIndex(s, "\n")+1:]
+}
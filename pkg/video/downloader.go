@@ -3,35 +3,66 @@ package video
 import (
 	"context"
 	"fmt"
-	"os"
-	"os/exec"
-	"path/filepath"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
 )
 
-type YouTubeDownloader struct {
-	TempDir string
+// Downloader fetches the video behind a URL and returns its local path.
+type Downloader interface {
+	Download(ctx context.Context, url string) (string, error)
 }
 
-func NewYouTubeDownloader(tempDir string) *YouTubeDownloader {
-	return &YouTubeDownloader{TempDir: tempDir}
+// SourceFactory creates a Downloader that writes into tempDir.
+type SourceFactory func(cfg *config.Config, tempDir string) Downloader
+
+var (
+	sourcesMu sync.RWMutex
+	sources   = map[string]SourceFactory{}
+)
+
+// RegisterSource registers a factory for URLs with the given scheme and host.
+// An empty host matches any host for the scheme. Subdomains of a registered
+// host match unless they are registered themselves.
+func RegisterSource(scheme, host string, factory SourceFactory) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+
+	sources[sourceKey(scheme, host)] = factory
 }
 
-func (yd *YouTubeDownloader) Download(ctx context.Context, url string) (string, error) {
-	if err := os.MkdirAll(yd.TempDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create temp dir: %w", err)
+// NewDownloader returns a Downloader for rawURL from the source registry.
+func NewDownloader(cfg *config.Config, rawURL string, tempDir string) (Downloader, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL format: %w", err)
 	}
 
-	tempFile := filepath.Join(yd.TempDir, "download.mp4")
+	sourcesMu.RLock()
+	defer sourcesMu.RUnlock()
 
-	cmd := exec.CommandContext(ctx, "yt-dlp", "-o", tempFile, url)
+	host := strings.ToLower(u.Hostname())
+	for {
+		if factory, ok := sources[sourceKey(u.Scheme, host)]; ok {
+			return factory(cfg, tempDir), nil
+		}
 
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("download failed: %w", err)
-	}
+		if host == "" {
+			break
+		}
 
-	if _, err := os.Stat(tempFile); err != nil {
-		return "", fmt.Errorf("downloaded file not found: %w", err)
+		if _, parent, ok := strings.Cut(host, "."); ok {
+			host = parent
+		} else {
+			host = ""
+		}
 	}
 
-	return tempFile, nil
+	return nil, fmt.Errorf("no source registered for %q", rawURL)
+}
+
+func sourceKey(scheme, host string) string {
+	return fmt.Sprintf("%s://%s", strings.ToLower(scheme), strings.ToLower(host))
 }
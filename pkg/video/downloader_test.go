@@ -0,0 +1,45 @@
+package video
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
+)
+
+func TestNewDownloader(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		want    Downloader
+		wantErr bool
+	}{
+		{name: "youtube", url: "https://youtube.com/watch?v=abc", want: &YouTubeDownloader{}},
+		{name: "youtube subdomain", url: "https://www.youtube.com/watch?v=abc", want: &YouTubeDownloader{}},
+		{name: "short link", url: "http://youtu.be/abc", want: &YouTubeDownloader{}},
+		{name: "host case", url: "https://WWW.YouTube.com/watch?v=abc", want: &YouTubeDownloader{}},
+		{name: "similar host", url: "https://notyoutube.com/watch", want: &WebDownloader{}},
+		{name: "any other host", url: "https://example.com/video.mp4", want: &WebDownloader{}},
+		{name: "s3", url: "s3://bucket/video.mp4", want: &S3Downloader{}},
+		{name: "local file", url: "file:///videos/video.mp4", want: &FileDownloader{}},
+		{name: "unknown scheme", url: "ftp://example.com/video.mp4", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewDownloader(&config.Config{}, tt.url, t.TempDir())
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NewDownloader returned a %T, want an error", got)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("NewDownloader: %v", err)
+			}
+
+			if reflect.TypeOf(got) != reflect.TypeOf(tt.want) {
+				t.Errorf("NewDownloader = %T, want %T", got, tt.want)
+			}
+		})
+	}
+}
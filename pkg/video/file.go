@@ -0,0 +1,71 @@
+package video
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
+)
+
+type FileDownloader struct{}
+
+func init() {
+	RegisterSource("file", "", func(cfg *config.Config, tempDir string) Downloader {
+		return &FileDownloader{}
+	})
+}
+
+// Download resolves a file:// URL to its path without copying the file.
+func (fd *FileDownloader) Download(ctx context.Context, rawURL string) (string, error) {
+	path, err := FilePath(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("file not found: %w", err)
+	} else if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", path)
+	}
+
+	return path, nil
+}
+
+// FileURL returns the file:// reference stored for a local video. Videos are
+// identified by the hash of their content, so the reference is updated
+// rather than the video indexed again when the file moves.
+func FileURL(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}
+
+	return u.String(), nil
+}
+
+// FilePath returns the local path referenced by a file:// URL.
+func FilePath(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL format: %w", err)
+	}
+
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("not a file URL: %s", rawURL)
+	}
+
+	return filepath.FromSlash(u.Path), nil
+}
+
+// IsLocal reports whether rawURL references a file on the local filesystem.
+func IsLocal(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+
+	return err == nil && u.Scheme == "file"
+}
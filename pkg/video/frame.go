@@ -3,42 +3,162 @@ package video
 import (
 	"context"
 	"fmt"
-	"log"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
-	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/ollama"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/llm"
 )
 
+const (
+	// KindVisual marks frames described by the vision model.
+	KindVisual = "visual"
+	// KindSpeech marks segments of what is said in the video.
+	KindSpeech = "speech"
+	// KindSegment marks frames described together with what is said while
+	// they are shown and the description of the frame before.
+	KindSegment = "segment"
+)
+
+// Frame is a moment of a video that is described and embedded. Speech
+// segments are frames without an image.
 type Frame struct {
+	Kind        string
 	Path        string
 	Timestamp   time.Duration
+	End         time.Duration
 	Description string
 	Embedding   []float32
+	// ImageEmbedding embeds the pixels of the frame when an image embedding
+	// model is configured.
+	ImageEmbedding []float32
+	// Analysis is the structured description in structured mode, from
+	// which Description is rendered.
+	Analysis *Analysis
+	// Title is the title of the video, for prompts that refer to it.
+	Title string
+	// Transcript and Previous give the vision model context in fusion mode.
+	Transcript string
+	Previous   string
 }
 
-func (f *Frame) Process(ctx context.Context, cfg *config.Config) error {
-	log.Printf("processing frame at path: %s", f.Path)
-
+// Process describes the frame with the vision model using prompt, as a
+// structured analysis in structured mode, and embeds the description, and the
+// pixels too when an image embedding model is set.
+func (f *Frame) Process(ctx context.Context, cfg *config.Config, models llm.Models, prompt *llm.Prompt) error {
 	data, err := os.ReadFile(f.Path)
 	if err != nil {
 		return fmt.Errorf("failed to read frame: %w", err)
 	}
 
-	desc, err := ollama.GetDescriptionFromImage(ctx, cfg, data)
+	if cfg.Fusion {
+		f.Kind = KindSegment
+	}
+
+	text, err := prompt.Render(llm.PromptData{
+		Timestamp:  f.Timestamp.String(),
+		Seconds:    f.Timestamp.Seconds(),
+		Title:      f.Title,
+		Transcript: f.Transcript,
+		Previous:   f.Previous,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get description: %w", err)
+		return err
+	}
+
+	if cfg.Structured {
+		answer, err := models.Vision.AnalyzeImage(ctx, text+"\n\n"+analysisPrompt, data, AnalysisSchema())
+		if err != nil {
+			return fmt.Errorf("failed to get analysis: %w", err)
+		}
+
+		f.Analysis, err = ParseAnalysis(answer)
+		if err != nil {
+			return err
+		}
+		f.Description = f.Analysis.Description()
+	} else {
+		desc, err := models.Vision.DescribeImage(ctx, text, data)
+		if err != nil {
+			return fmt.Errorf("failed to get description: %w", err)
+		}
+		f.Description = desc
 	}
-	f.Description = desc
 
-	embedding, err := ollama.GetTextEmbedding(ctx, cfg, desc)
+	embedding, err := models.Embedding.Embed(ctx, f.Description)
 	if err != nil {
 		return fmt.Errorf("failed to get embedding: %w", err)
 	}
 	f.Embedding = embedding
 
-	log.Printf("processed frame, description: %q, embedding dim: %d", f.Description, len(f.Embedding))
+	if models.Image != nil {
+		embedding, err := models.Image.EmbedImage(ctx, data)
+		if err != nil {
+			return fmt.Errorf("failed to get image embedding: %w", err)
+		}
+		f.ImageEmbedding = embedding
+	}
+
+	return nil
+}
+
+// ProcessAll processes frames on up to workers goroutines and calls done for
+// every frame in timestamp order as soon as it and its predecessors finish.
+// In fusion mode every description builds on the one before, so frames are
+// processed one at a time.
+func ProcessAll(ctx context.Context, cfg *config.Config, models llm.Models, prompt *llm.Prompt, frames []Frame, workers int, done func(f *Frame, err error)) error {
+	workers = max(1, min(workers, len(frames)))
+	if cfg.Fusion {
+		workers = 1
+	}
+
+	errs := make([]error, len(frames))
+	finished := make([]chan struct{}, len(frames))
+	for i := range finished {
+		finished[i] = make(chan struct{})
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	indices := make(chan int)
+	go func() {
+		defer close(indices)
+		for i := range frames {
+			select {
+			case indices <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				if cfg.Fusion && i > 0 {
+					frames[i].Previous = frames[i-1].Description
+				}
+				errs[i] = frames[i].Process(ctx, cfg, models, prompt)
+				close(finished[i])
+			}
+		}()
+	}
+	defer wg.Wait()
+
+	for i := range frames {
+		select {
+		case <-finished[i]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		done(&frames[i], errs[i])
+	}
 
 	return nil
 }
@@ -0,0 +1,124 @@
+package video
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+const httpDownloadAttempts = 3
+
+// HTTPDownloader fetches a video file over plain HTTP. It refuses web pages,
+// which need yt-dlp to find the video they embed.
+type HTTPDownloader struct {
+	TempDir string
+	Client  *http.Client
+	// Sign, if set, is applied to every request before it is sent.
+	Sign func(req *http.Request) error
+}
+
+// errWebPage is returned for URLs that serve a web page instead of a video.
+var errWebPage = errors.New("url serves a web page, not a video; video pages are downloaded with yt-dlp")
+
+func NewHTTPDownloader(tempDir string) *HTTPDownloader {
+	return &HTTPDownloader{
+		TempDir: tempDir,
+		Client:  http.DefaultClient,
+	}
+}
+
+// Download fetches url into the temp dir. Interrupted transfers are resumed
+// with range requests from the partial file left behind.
+func (hd *HTTPDownloader) Download(ctx context.Context, url string) (string, error) {
+	if err := os.MkdirAll(hd.TempDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create temp dir: %w", err)
+	}
+
+	tempFile := filepath.Join(hd.TempDir, "download.mp4")
+	partFile := tempFile + ".part"
+
+	var err error
+	for attempt := 1; attempt <= httpDownloadAttempts; attempt++ {
+		if err = hd.fetch(ctx, url, partFile); err == nil {
+			break
+		}
+
+		if ctx.Err() != nil || errors.Is(err, errWebPage) {
+			return "", fmt.Errorf("download failed: %w", err)
+		}
+
+		log.Printf("download attempt %d of %s failed: %v", attempt, url, err)
+	}
+	if err != nil {
+		return "", fmt.Errorf("download failed: %w", err)
+	}
+
+	if err := os.Rename(partFile, tempFile); err != nil {
+		return "", fmt.Errorf("failed to finalize download: %w", err)
+	}
+
+	return tempFile, nil
+}
+
+func (hd *HTTPDownloader) fetch(ctx context.Context, url string, path string) error {
+	var offset int64
+	if info, err := os.Stat(path); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	if hd.Sign != nil {
+		if err := hd.Sign(req); err != nil {
+			return fmt.Errorf("failed to sign request: %w", err)
+		}
+	}
+
+	rep, err := hd.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer rep.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch rep.StatusCode {
+	case http.StatusOK:
+		flags |= os.O_TRUNC
+	case http.StatusPartialContent:
+		flags |= os.O_APPEND
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the partial file already holds the whole body
+		return nil
+	default:
+		body, _ := io.ReadAll(io.LimitReader(rep.Body, 1024))
+		return fmt.Errorf("unexpected status: %s (%d)", string(body), rep.StatusCode)
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(rep.Header.Get("Content-Type")); mediaType == "text/html" {
+		return errWebPage
+	}
+
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, rep.Body); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package video
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestHTTPDownloaderContentType(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		wantErr     error
+	}{
+		{name: "video", contentType: "video/mp4"},
+		{name: "binary", contentType: "application/octet-stream"},
+		{name: "web page", contentType: "text/html; charset=utf-8", wantErr: errWebPage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write([]byte("body"))
+			}))
+			defer srv.Close()
+
+			path, err := NewHTTPDownloader(t.TempDir()).Download(context.Background(), srv.URL)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Download error = %v, want %v", err, tt.wantErr)
+				} else if requests != 1 {
+					t.Errorf("Download sent %d requests, want 1", requests)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("Download: %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			} else if string(data) != "body" {
+				t.Errorf("downloaded %q, want %q", data, "body")
+			}
+		})
+	}
+}
+
+func TestWebDownloaderServesVideo(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		status      int
+		want        bool
+	}{
+		{name: "video", contentType: "video/mp4", status: http.StatusOK, want: true},
+		{name: "web page", contentType: "text/html", status: http.StatusOK},
+		{name: "binary", contentType: "application/octet-stream", status: http.StatusOK},
+		{name: "head not allowed", contentType: "video/mp4", status: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodHead {
+					t.Errorf("request method = %s, want HEAD", r.Method)
+				}
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			wd := &WebDownloader{http: NewHTTPDownloader(t.TempDir())}
+			if got := wd.servesVideo(context.Background(), srv.URL); got != tt.want {
+				t.Errorf("servesVideo = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
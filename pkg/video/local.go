@@ -0,0 +1,59 @@
+package video
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var DefaultExtensions = []string{".avi", ".m4v", ".mkv", ".mov", ".mp4", ".webm"}
+
+// Find returns the video files at path. A file is returned as is, while a
+// directory is walked recursively for files matching the given extensions.
+func Find(path string, extensions []string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	if len(extensions) == 0 {
+		extensions = DefaultExtensions
+	}
+
+	allowed := make([]string, 0, len(extensions))
+	for _, ext := range extensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		allowed = append(allowed, ext)
+	}
+
+	var paths []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.Type().IsRegular() && slices.Contains(allowed, strings.ToLower(filepath.Ext(p))) {
+			paths = append(paths, p)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory: %w", err)
+	}
+
+	return paths, nil
+}
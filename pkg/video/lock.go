@@ -0,0 +1,56 @@
+package video
+
+import (
+	"context"
+	"sync"
+)
+
+type videoLock struct {
+	sem  chan struct{}
+	refs int
+}
+
+var (
+	locksMu sync.Mutex
+	locks   = map[string]*videoLock{}
+)
+
+// Lock blocks until no other job in this process holds the video, so the
+// same video is never extracted into its processing path twice at once.
+func (v *Video) Lock(ctx context.Context) error {
+	locksMu.Lock()
+	l, ok := locks[v.ID]
+	if !ok {
+		l = &videoLock{sem: make(chan struct{}, 1)}
+		locks[v.ID] = l
+	}
+	l.refs++
+	locksMu.Unlock()
+
+	select {
+	case l.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		release(v.ID, l)
+		return ctx.Err()
+	}
+}
+
+func (v *Video) Unlock() {
+	locksMu.Lock()
+	l := locks[v.ID]
+	locksMu.Unlock()
+
+	<-l.sem
+	release(v.ID, l)
+}
+
+func release(id string, l *videoLock) {
+	locksMu.Lock()
+	defer locksMu.Unlock()
+
+	l.refs--
+	if l.refs == 0 {
+		delete(locks, id)
+	}
+}
@@ -0,0 +1,223 @@
+package video
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxThumbnails caps the thumbnails kept per video, best first.
+const maxThumbnails = 3
+
+// Metadata is the catalog record of a video, merged from what its source
+// reports and what ffprobe finds in the file.
+type Metadata struct {
+	Title      string    `json:"title,omitempty"`
+	Uploader   string    `json:"uploader,omitempty"`
+	UploadDate string    `json:"upload_date,omitempty"`
+	Duration   float64   `json:"duration,omitempty"`
+	Width      int       `json:"width,omitempty"`
+	Height     int       `json:"height,omitempty"`
+	FPS        float64   `json:"fps,omitempty"`
+	Codec      string    `json:"codec,omitempty"`
+	Chapters   []Chapter `json:"chapters,omitempty"`
+	Thumbnails []string  `json:"thumbnails,omitempty"`
+}
+
+type Chapter struct {
+	Title string  `json:"title"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// MetadataSource is implemented by downloaders that learn about a video
+// while downloading it.
+type MetadataSource interface {
+	// Metadata returns what the last download reported, or nil.
+	Metadata() *Metadata
+}
+
+// Merge fills the fields of m that are empty from other.
+func (m *Metadata) Merge(other *Metadata) {
+	if other == nil {
+		return
+	}
+
+	m.Title = cmp.Or(m.Title, other.Title)
+	m.Uploader = cmp.Or(m.Uploader, other.Uploader)
+	m.UploadDate = cmp.Or(m.UploadDate, other.UploadDate)
+	m.Duration = cmp.Or(m.Duration, other.Duration)
+	m.Width = cmp.Or(m.Width, other.Width)
+	m.Height = cmp.Or(m.Height, other.Height)
+	m.FPS = cmp.Or(m.FPS, other.FPS)
+	m.Codec = cmp.Or(m.Codec, other.Codec)
+	if len(m.Chapters) == 0 {
+		m.Chapters = other.Chapters
+	}
+	if len(m.Thumbnails) == 0 {
+		m.Thumbnails = other.Thumbnails
+	}
+}
+
+// Resolution formats the frame size as WIDTHxHEIGHT.
+func (m *Metadata) Resolution() string {
+	if m.Width == 0 || m.Height == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%dx%d", m.Width, m.Height)
+}
+
+// Probe reads the technical metadata of a video file with ffprobe.
+func Probe(ctx context.Context, path string) (*Metadata, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		"-show_chapters",
+		path,
+	)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffprobe error: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return parseProbe(stdout.Bytes())
+}
+
+func parseProbe(data []byte) (*Metadata, error) {
+	var probe struct {
+		Format struct {
+			Duration string            `json:"duration"`
+			Tags     map[string]string `json:"tags"`
+		} `json:"format"`
+		Streams []struct {
+			CodecType    string `json:"codec_type"`
+			CodecName    string `json:"codec_name"`
+			Width        int    `json:"width"`
+			Height       int    `json:"height"`
+			AvgFrameRate string `json:"avg_frame_rate"`
+		} `json:"streams"`
+		Chapters []struct {
+			StartTime string            `json:"start_time"`
+			EndTime   string            `json:"end_time"`
+			Tags      map[string]string `json:"tags"`
+		} `json:"chapters"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+
+	m := &Metadata{
+		Title: probe.Format.Tags["title"],
+	}
+	m.Duration, _ = strconv.ParseFloat(probe.Format.Duration, 64)
+
+	for _, s := range probe.Streams {
+		if s.CodecType != "video" {
+			continue
+		}
+
+		m.Width = s.Width
+		m.Height = s.Height
+		m.Codec = s.CodecName
+		m.FPS = parseRate(s.AvgFrameRate)
+		break
+	}
+
+	for _, c := range probe.Chapters {
+		start, _ := strconv.ParseFloat(c.StartTime, 64)
+		end, _ := strconv.ParseFloat(c.EndTime, 64)
+		m.Chapters = append(m.Chapters, Chapter{Title: c.Tags["title"], Start: start, End: end})
+	}
+
+	return m, nil
+}
+
+// parseYtDlpInfo reads the info JSON yt-dlp prints for a download.
+func parseYtDlpInfo(data []byte) (*Metadata, error) {
+	var info struct {
+		Title      string  `json:"title"`
+		Uploader   string  `json:"uploader"`
+		Channel    string  `json:"channel"`
+		UploadDate string  `json:"upload_date"`
+		Duration   float64 `json:"duration"`
+		Width      int     `json:"width"`
+		Height     int     `json:"height"`
+		FPS        float64 `json:"fps"`
+		VCodec     string  `json:"vcodec"`
+		Chapters   []struct {
+			Title     string  `json:"title"`
+			StartTime float64 `json:"start_time"`
+			EndTime   float64 `json:"end_time"`
+		} `json:"chapters"`
+		Thumbnail  string `json:"thumbnail"`
+		Thumbnails []struct {
+			URL string `json:"url"`
+		} `json:"thumbnails"`
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse yt-dlp info: %w", err)
+	}
+
+	m := &Metadata{
+		Title:    info.Title,
+		Uploader: cmp.Or(info.Channel, info.Uploader),
+		Duration: info.Duration,
+		Width:    info.Width,
+		Height:   info.Height,
+		FPS:      info.FPS,
+		Codec:    info.VCodec,
+	}
+
+	if date, err := time.Parse("20060102", info.UploadDate); err == nil {
+		m.UploadDate = date.Format(time.DateOnly)
+	}
+
+	for _, c := range info.Chapters {
+		m.Chapters = append(m.Chapters, Chapter{Title: c.Title, Start: c.StartTime, End: c.EndTime})
+	}
+
+	// yt-dlp lists thumbnails from worst to best
+	if info.Thumbnail != "" {
+		m.Thumbnails = append(m.Thumbnails, info.Thumbnail)
+	}
+	for _, t := range slices.Backward(info.Thumbnails) {
+		if len(m.Thumbnails) == maxThumbnails {
+			break
+		} else if t.URL != "" && !slices.Contains(m.Thumbnails, t.URL) {
+			m.Thumbnails = append(m.Thumbnails, t.URL)
+		}
+	}
+
+	return m, nil
+}
+
+// parseRate parses an ffprobe frame rate such as "30000/1001".
+func parseRate(rate string) float64 {
+	num, den, ok := strings.Cut(rate, "/")
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0
+	} else if !ok {
+		return n
+	}
+
+	d, err := strconv.ParseFloat(den, 64)
+	if err != nil || d == 0 {
+		return 0
+	}
+
+	return n / d
+}
@@ -0,0 +1,154 @@
+package video
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseProbe(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    *Metadata
+		wantErr bool
+	}{
+		{
+			name: "video with chapters",
+			data: `{
+				"streams": [
+					{"codec_type": "audio", "codec_name": "aac"},
+					{"codec_type": "video", "codec_name": "h264", "width": 1920, "height": 1080, "avg_frame_rate": "30000/1001"}
+				],
+				"chapters": [
+					{"start_time": "0.000000", "end_time": "60.500000", "tags": {"title": "Intro"}}
+				],
+				"format": {"duration": "120.250000", "tags": {"title": "Talk"}}
+			}`,
+			want: &Metadata{
+				Title:    "Talk",
+				Duration: 120.25,
+				Width:    1920,
+				Height:   1080,
+				FPS:      30000.0 / 1001,
+				Codec:    "h264",
+				Chapters: []Chapter{{Title: "Intro", Start: 0, End: 60.5}},
+			},
+		},
+		{
+			name: "no video stream",
+			data: `{"streams": [{"codec_type": "audio", "codec_name": "mp3"}], "format": {"duration": "N/A"}}`,
+			want: &Metadata{},
+		},
+		{
+			name: "unknown frame rate",
+			data: `{"streams": [{"codec_type": "video", "avg_frame_rate": "0/0"}], "format": {}}`,
+			want: &Metadata{},
+		},
+		{
+			name:    "not json",
+			data:    "ffprobe: command not found",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseProbe([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("parseProbe succeeded, want an error")
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("parseProbe: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseProbe = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYtDlpInfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    *Metadata
+		wantErr bool
+	}{
+		{
+			name: "complete",
+			data: `{
+				"title": "Talk",
+				"uploader": "someone",
+				"channel": "Conference",
+				"upload_date": "20240131",
+				"duration": 120,
+				"width": 1280,
+				"height": 720,
+				"fps": 25,
+				"vcodec": "avc1.64001F",
+				"chapters": [{"title": "Intro", "start_time": 0, "end_time": 30}],
+				"thumbnail": "https://img/best.jpg",
+				"thumbnails": [
+					{"url": "https://img/1.jpg"},
+					{"url": "https://img/2.jpg"},
+					{"url": "https://img/3.jpg"},
+					{"url": "https://img/best.jpg"}
+				]
+			}`,
+			want: &Metadata{
+				Title:      "Talk",
+				Uploader:   "Conference",
+				UploadDate: "2024-01-31",
+				Duration:   120,
+				Width:      1280,
+				Height:     720,
+				FPS:        25,
+				Codec:      "avc1.64001F",
+				Chapters:   []Chapter{{Title: "Intro", Start: 0, End: 30}},
+				Thumbnails: []string{"https://img/best.jpg", "https://img/3.jpg", "https://img/2.jpg"},
+			},
+		},
+		{
+			name: "uploader without channel",
+			data: `{"title": "Clip", "uploader": "someone", "upload_date": "yesterday"}`,
+			want: &Metadata{Title: "Clip", Uploader: "someone"},
+		},
+		{
+			name:    "not json",
+			data:    "ERROR: Unsupported URL",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYtDlpInfo([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("parseYtDlpInfo succeeded, want an error")
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("parseYtDlpInfo: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseYtDlpInfo = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetadataMerge(t *testing.T) {
+	m := &Metadata{Title: "From source", Width: 640}
+	m.Merge(&Metadata{Title: "From file", Width: 1280, Height: 720, Codec: "h264"})
+	m.Merge(nil)
+
+	want := &Metadata{Title: "From source", Width: 640, Height: 720, Codec: "h264"}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("Merge = %+v, want %+v", m, want)
+	}
+}
@@ -0,0 +1,96 @@
+package video
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math/bits"
+	"os"
+)
+
+// dHash computes the 64-bit difference hash of an image: the image is
+// reduced to a 9x8 grayscale grid and every bit records whether a cell is
+// brighter than its right neighbour.
+func dHash(img image.Image) uint64 {
+	const w, h = 9, 8
+
+	var (
+		sums   [h][w]float64
+		counts [h][w]float64
+	)
+
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		cy := (y - b.Min.Y) * h / b.Dy()
+		for x := b.Min.X; x < b.Max.X; x++ {
+			cx := (x - b.Min.X) * w / b.Dx()
+
+			r, g, bl, _ := img.At(x, y).RGBA()
+			sums[cy][cx] += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)
+			counts[cy][cx]++
+		}
+	}
+
+	var hash uint64
+	for y := range h {
+		for x := range w - 1 {
+			left := sums[y][x] / max(counts[y][x], 1)
+			right := sums[y][x+1] / max(counts[y][x+1], 1)
+
+			hash <<= 1
+			if left > right {
+				hash |= 1
+			}
+		}
+	}
+
+	return hash
+}
+
+func hashFrame(path string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode frame: %w", err)
+	}
+
+	return dHash(img), nil
+}
+
+// Deduplicate collapses runs of visually similar frames into the first frame
+// of the run, extending its end timestamp to the last similar frame. Frames
+// are similar when their hashes differ in at most threshold bits; a negative
+// threshold disables deduplication.
+func (v *Video) Deduplicate(threshold int) error {
+	if threshold < 0 || len(v.Frames) == 0 {
+		return nil
+	}
+
+	res := make([]Frame, 0, len(v.Frames))
+
+	var current uint64
+	for i, frame := range v.Frames {
+		h, err := hashFrame(frame.Path)
+		if err != nil {
+			return fmt.Errorf("failed to hash frame %s: %w", frame.Path, err)
+		}
+
+		if i > 0 && bits.OnesCount64(current^h) <= threshold {
+			res[len(res)-1].End = frame.End
+			continue
+		}
+
+		current = h
+		res = append(res, frame)
+	}
+
+	v.Frames = res
+
+	return nil
+}
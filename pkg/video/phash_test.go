@@ -0,0 +1,134 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// gradient returns an image whose brightness rises from left to right, or
+// from right to left when reversed.
+func gradient(reversed bool) image.Image {
+	img := image.NewGray(image.Rect(0, 0, 90, 40))
+	for y := range 40 {
+		for x := range 90 {
+			v := uint8(x * 255 / 89)
+			if reversed {
+				v = 255 - v
+			}
+			img.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+
+	return img
+}
+
+func writePNG(t *testing.T, img image.Image) string {
+	t.Helper()
+
+	f, err := os.CreateTemp(t.TempDir(), "frame-*.png")
+	if err != nil {
+		t.Fatalf("CreateTemp: %v", err)
+	}
+	defer f.Close()
+
+	if err := png.Encode(f, img); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	return f.Name()
+}
+
+func TestDHash(t *testing.T) {
+	tests := []struct {
+		name string
+		img  image.Image
+		want uint64
+	}{
+		{name: "brightening", img: gradient(false), want: 0},
+		{name: "darkening", img: gradient(true), want: 1<<64 - 1},
+		{name: "flat", img: image.NewGray(image.Rect(0, 0, 16, 16)), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dHash(tt.img); got != tt.want {
+				t.Errorf("dHash = %#x, want %#x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeduplicate(t *testing.T) {
+	light, dark := writePNG(t, gradient(false)), writePNG(t, gradient(true))
+	frame := func(path string, seconds int) Frame {
+		ts := time.Duration(seconds) * time.Second
+		return Frame{Path: path, Timestamp: ts, End: ts}
+	}
+
+	tests := []struct {
+		name      string
+		frames    []Frame
+		threshold int
+		want      []Frame
+		wantErr   bool
+	}{
+		{
+			name:      "collapses runs",
+			frames:    []Frame{frame(light, 0), frame(light, 1), frame(dark, 2), frame(dark, 3), frame(light, 4)},
+			threshold: 5,
+			want: []Frame{
+				{Path: light, Timestamp: 0, End: time.Second},
+				{Path: dark, Timestamp: 2 * time.Second, End: 3 * time.Second},
+				frame(light, 4),
+			},
+		},
+		{
+			name:      "disabled",
+			frames:    []Frame{frame(light, 0), frame(light, 1)},
+			threshold: -1,
+			want:      []Frame{frame(light, 0), frame(light, 1)},
+		},
+		{
+			name:      "threshold covers every bit",
+			frames:    []Frame{frame(light, 0), frame(dark, 1)},
+			threshold: 64,
+			want:      []Frame{{Path: light, Timestamp: 0, End: time.Second}},
+		},
+		{
+			name:      "missing frame",
+			frames:    []Frame{frame(filepath.Join(t.TempDir(), "missing.png"), 0)},
+			threshold: 5,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &Video{Frames: tt.frames}
+
+			err := v.Deduplicate(tt.threshold)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Deduplicate succeeded, want an error")
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("Deduplicate: %v", err)
+			}
+
+			if len(v.Frames) != len(tt.want) {
+				t.Fatalf("Deduplicate kept %d frames, want %d", len(v.Frames), len(tt.want))
+			}
+			for i, frame := range v.Frames {
+				if frame.Path != tt.want[i].Path || frame.Timestamp != tt.want[i].Timestamp || frame.End != tt.want[i].End {
+					t.Errorf("frame %d = %+v, want %+v", i, frame, tt.want[i])
+				}
+			}
+		})
+	}
+}
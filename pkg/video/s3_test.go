@@ -0,0 +1,79 @@
+package video
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
+)
+
+func TestS3Sign(t *testing.T) {
+	cfg := &config.Config{
+		S3Endpoint:  "http://localhost:9000/",
+		S3AccessKey: "access",
+		S3SecretKey: "secret",
+	}
+
+	tests := []struct {
+		name     string
+		cfg      *config.Config
+		wantDate string
+		wantAuth string
+	}{
+		{
+			name:     "signed",
+			cfg:      cfg,
+			wantDate: "20250102T030405Z",
+			wantAuth: "AWS4-HMAC-SHA256 Credential=access/20250102/us-east-1/s3/aws4_request, " +
+				"SignedHeaders=host;x-amz-content-sha256;x-amz-date, " +
+				"Signature=b690cac02580e8dc2dd7622ebc3380a0f04808a59d2523d5e2e08577f7fb0645",
+		},
+		{
+			name: "anonymous",
+			cfg:  &config.Config{S3Endpoint: cfg.S3Endpoint},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sd := NewS3Downloader(tt.cfg, t.TempDir())
+
+			req, err := http.NewRequest("GET", sd.Endpoint+"/videos/clip%20one.mp4", nil)
+			if err != nil {
+				t.Fatalf("NewRequest: %v", err)
+			}
+
+			// the time is in another zone to check that it is signed in UTC
+			at := time.Date(2025, 1, 2, 4, 4, 5, 0, time.FixedZone("CET", 3600))
+			if err := sd.signAt(req, at); err != nil {
+				t.Fatalf("signAt: %v", err)
+			}
+
+			if got := req.Header.Get("X-Amz-Date"); got != tt.wantDate {
+				t.Errorf("X-Amz-Date = %q, want %q", got, tt.wantDate)
+			}
+			if got := req.Header.Get("Authorization"); got != tt.wantAuth {
+				t.Errorf("Authorization = %q, want %q", got, tt.wantAuth)
+			}
+		})
+	}
+}
+
+func TestAWSEscape(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"clip.mp4", "clip.mp4"},
+		{"my clip+1.mp4", "my%20clip%2B1.mp4"},
+		{"a~b_c-d", "a~b_c-d"},
+		{"é", "%C3%A9"},
+	}
+
+	for _, tt := range tests {
+		if got := awsEscape(tt.in); got != tt.want {
+			t.Errorf("awsEscape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package video
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
+)
+
+const (
+	SamplingInterval  = "interval"
+	SamplingScene     = "scene"
+	SamplingKeyframes = "keyframes"
+	SamplingHybrid    = "hybrid"
+)
+
+var SamplingModes = []string{SamplingInterval, SamplingScene, SamplingKeyframes, SamplingHybrid}
+
+// showinfoPattern matches the per-frame lines the showinfo filter logs.
+var showinfoPattern = regexp.MustCompile(`\bn:\s*(\d+)\s+pts:\s*-?\d+\s+pts_time:(-?[\d.]+)`)
+
+// samplingArgs returns the ffmpeg input options and video filter selecting
+// frames for the configured sampling mode. Every filter ends in showinfo so
+// the exact timestamp of each selected frame can be read from the log.
+func samplingArgs(cfg *config.Config) ([]string, string, error) {
+	switch cfg.SamplingMode {
+	case SamplingInterval, "":
+		if cfg.SamplingInterval < 1 {
+			return nil, "", fmt.Errorf("sampling interval must be positive, got %d", cfg.SamplingInterval)
+		}
+		return nil, fmt.Sprintf("fps=1/%d,showinfo", cfg.SamplingInterval), nil
+	case SamplingScene:
+		return nil, fmt.Sprintf("select='eq(n,0)+gt(scene,%g)',showinfo", cfg.SceneThreshold), nil
+	case SamplingKeyframes:
+		return []string{"-skip_frame", "nokey"}, "showinfo", nil
+	case SamplingHybrid:
+		if cfg.MinGap > cfg.MaxGap {
+			return nil, "", fmt.Errorf("minimum gap %gs exceeds maximum gap %gs", cfg.MinGap, cfg.MaxGap)
+		}
+		return nil, fmt.Sprintf(
+			"select='isnan(prev_selected_t)+gte(t-prev_selected_t,%g)+gt(scene,%g)*gte(t-prev_selected_t,%g)',showinfo",
+			cfg.MaxGap, cfg.SceneThreshold, cfg.MinGap,
+		), nil
+	default:
+		return nil, "", fmt.Errorf("unknown sampling mode %q", cfg.SamplingMode)
+	}
+}
+
+// parseShowinfo returns the presentation timestamps logged by showinfo,
+// indexed by output frame number.
+func parseShowinfo(log []byte) map[int]time.Duration {
+	res := map[int]time.Duration{}
+
+	for _, m := range showinfoPattern.FindAllSubmatch(log, -1) {
+		n, err := strconv.Atoi(string(m[1]))
+		if err != nil {
+			continue
+		}
+
+		secs, err := strconv.ParseFloat(string(m[2]), 64)
+		if err != nil {
+			continue
+		}
+
+		res[n] = time.Duration(secs * float64(time.Second))
+	}
+
+	return res
+}
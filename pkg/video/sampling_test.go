@@ -0,0 +1,100 @@
+package video
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
+)
+
+func TestSamplingArgs(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        config.Config
+		wantInput  []string
+		wantFilter string
+		wantErr    bool
+	}{
+		{
+			name:       "interval",
+			cfg:        config.Config{SamplingMode: SamplingInterval, SamplingInterval: 5},
+			wantFilter: "fps=1/5,showinfo",
+		},
+		{
+			name:       "default mode",
+			cfg:        config.Config{SamplingInterval: 2},
+			wantFilter: "fps=1/2,showinfo",
+		},
+		{
+			name:    "zero interval",
+			cfg:     config.Config{SamplingMode: SamplingInterval},
+			wantErr: true,
+		},
+		{
+			name:       "scene",
+			cfg:        config.Config{SamplingMode: SamplingScene, SceneThreshold: 0.3},
+			wantFilter: "select='eq(n,0)+gt(scene,0.3)',showinfo",
+		},
+		{
+			name:       "keyframes",
+			cfg:        config.Config{SamplingMode: SamplingKeyframes},
+			wantInput:  []string{"-skip_frame", "nokey"},
+			wantFilter: "showinfo",
+		},
+		{
+			name:       "hybrid",
+			cfg:        config.Config{SamplingMode: SamplingHybrid, SceneThreshold: 0.4, MinGap: 1, MaxGap: 10},
+			wantFilter: "select='isnan(prev_selected_t)+gte(t-prev_selected_t,10)+gt(scene,0.4)*gte(t-prev_selected_t,1)',showinfo",
+		},
+		{
+			name:    "hybrid gaps reversed",
+			cfg:     config.Config{SamplingMode: SamplingHybrid, MinGap: 10, MaxGap: 1},
+			wantErr: true,
+		},
+		{
+			name:    "unknown mode",
+			cfg:     config.Config{SamplingMode: "random"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, filter, err := samplingArgs(&tt.cfg)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("samplingArgs succeeded, want an error")
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("samplingArgs: %v", err)
+			}
+
+			if !reflect.DeepEqual(input, tt.wantInput) {
+				t.Errorf("input args = %q, want %q", input, tt.wantInput)
+			}
+			if filter != tt.wantFilter {
+				t.Errorf("filter = %q, want %q", filter, tt.wantFilter)
+			}
+		})
+	}
+}
+
+func TestParseShowinfo(t *testing.T) {
+	log := []byte(`[Parsed_showinfo_1 @ 0x5581] config in time_base: 1/15360, frame_rate: 30/1
+[Parsed_showinfo_1 @ 0x5581] n:   0 pts:      0 pts_time:0       duration:512 checksum:0A1B2C3D
+[Parsed_showinfo_1 @ 0x5581] n:   1 pts: 153600 pts_time:10      duration:512 checksum:0A1B2C3D
+frame=    2 fps=0.0 q=-0.0 size=N/A time=00:00:10.03 bitrate=N/A speed=  20x
+[Parsed_showinfo_1 @ 0x5581] n:  12 pts: 191232 pts_time:12.45   duration:512 checksum:0A1B2C3D
+`)
+
+	want := map[int]time.Duration{
+		0:  0,
+		1:  10 * time.Second,
+		12: 12*time.Second + 450*time.Millisecond,
+	}
+	if got := parseShowinfo(log); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseShowinfo = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,189 @@
+package video
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cue is a single timed caption.
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+// SubtitleSource is implemented by downloaders that can fetch the captions
+// of a video alongside it.
+type SubtitleSource interface {
+	// Subtitles returns the path of the captions fetched by the last
+	// download, or "" if there were none.
+	Subtitles() string
+}
+
+var (
+	cueTiming = regexp.MustCompile(`^(\S+)\s+-->\s+(\S+)`)
+	cueTags   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// ParseSubtitles reads a WebVTT or SubRip file. Lines repeated from the
+// previous cue, as in YouTube's rolling auto-captions, are dropped.
+func ParseSubtitles(path string) ([]Cue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read subtitles: %w", err)
+	}
+
+	cues, err := parseCues(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+
+	return cues, nil
+}
+
+func parseCues(data []byte) ([]Cue, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	var (
+		cues []Cue
+		cue  *Cue
+		last string
+	)
+	flush := func() {
+		if cue != nil && cue.Text != "" {
+			cues = append(cues, *cue)
+		}
+		cue = nil
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		raw := strings.TrimRight(sc.Text(), "\r")
+		line := strings.TrimSpace(raw)
+
+		if m := cueTiming.FindStringSubmatch(line); m != nil {
+			flush()
+
+			start, err := parseCueTime(m[1])
+			if err != nil {
+				return nil, err
+			}
+			end, err := parseCueTime(m[2])
+			if err != nil {
+				return nil, err
+			}
+
+			cue = &Cue{Start: start, End: end}
+			continue
+		}
+
+		// empty lines end a cue, while YouTube pads some cues with lines of
+		// spaces; headers, notes and SubRip indices come before any timing
+		// line and are skipped with it
+		if raw == "" {
+			flush()
+			continue
+		} else if cue == nil {
+			continue
+		}
+
+		text := strings.TrimSpace(cueTags.ReplaceAllString(line, ""))
+		if text == "" || text == last {
+			continue
+		}
+		last = text
+
+		if cue.Text != "" {
+			cue.Text += " "
+		}
+		cue.Text += text
+	}
+	flush()
+
+	return cues, sc.Err()
+}
+
+// parseCueTime parses [hh:]mm:ss.mmm, accepting SubRip's comma separator.
+func parseCueTime(s string) (time.Duration, error) {
+	parts := strings.Split(strings.Replace(s, ",", ".", 1), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid cue time %q", s)
+	}
+
+	var d time.Duration
+	for i, p := range parts {
+		unit := time.Minute
+		if i == len(parts)-1 {
+			unit = time.Second
+		} else if len(parts) == 3 && i == 0 {
+			unit = time.Hour
+		}
+
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid cue time %q", s)
+		}
+		d += time.Duration(v * float64(unit))
+	}
+
+	return d, nil
+}
+
+// ChunkCues groups cues into speech segments spanning about window each, so
+// every segment carries enough context to be worth embedding.
+func ChunkCues(cues []Cue, window time.Duration) []Frame {
+	var (
+		res []Frame
+		seg *Frame
+	)
+	for _, cue := range cues {
+		if seg != nil && cue.Start-seg.Timestamp >= window {
+			res = append(res, *seg)
+			seg = nil
+		}
+
+		if seg == nil {
+			seg = &Frame{Kind: KindSpeech, Timestamp: cue.Start}
+		} else {
+			seg.Description += " "
+		}
+
+		seg.Description += cue.Text
+		seg.End = max(seg.End, cue.End)
+	}
+
+	if seg != nil {
+		res = append(res, *seg)
+	}
+
+	return res
+}
+
+// AttachTranscript sets the transcript of every frame to what is said from
+// its timestamp until the next frame, or for tail after the last one.
+func AttachTranscript(frames []Frame, cues []Cue, tail time.Duration) {
+	for i := range frames {
+		start := frames[i].Timestamp
+		end := max(frames[i].End, start) + tail
+		if i+1 < len(frames) {
+			end = frames[i+1].Timestamp
+		}
+
+		var texts []string
+		for _, cue := range cues {
+			if cue.End > start && cue.Start < end {
+				texts = append(texts, cue.Text)
+			}
+		}
+
+		frames[i].Transcript = strings.Join(texts, " ")
+	}
+}
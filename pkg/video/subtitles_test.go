@@ -0,0 +1,132 @@
+package video
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseCues(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []Cue
+		wantErr bool
+	}{
+		{
+			name: "webvtt",
+			data: "WEBVTT\nKind: captions\n\n00:00:01.000 --> 00:00:02.500\nHello\nworld\n\n00:00:03.000 --> 00:00:04.000 align:start\n<c>Bye</c>\n",
+			want: []Cue{
+				{Start: time.Second, End: 2500 * time.Millisecond, Text: "Hello world"},
+				{Start: 3 * time.Second, End: 4 * time.Second, Text: "Bye"},
+			},
+		},
+		{
+			name: "subrip",
+			data: "1\r\n00:01:00,250 --> 00:01:01,000\r\nFirst\r\n\r\n2\r\n01:00:00,000 --> 01:00:01,000\r\nSecond\r\n",
+			want: []Cue{
+				{Start: time.Minute + 250*time.Millisecond, End: time.Minute + time.Second, Text: "First"},
+				{Start: time.Hour, End: time.Hour + time.Second, Text: "Second"},
+			},
+		},
+		{
+			name: "byte order mark and short times",
+			data: "\ufeffWEBVTT\n\n00:05.000 --> 00:06.000\nShort\n",
+			want: []Cue{
+				{Start: 5 * time.Second, End: 6 * time.Second, Text: "Short"},
+			},
+		},
+		{
+			name: "rolling captions",
+			data: "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\none\n\n00:00:02.000 --> 00:00:03.000\none\ntwo\n   \n\n00:00:03.000 --> 00:00:04.000\ntwo\n",
+			want: []Cue{
+				{Start: time.Second, End: 2 * time.Second, Text: "one"},
+				{Start: 2 * time.Second, End: 3 * time.Second, Text: "two"},
+			},
+		},
+		{
+			name: "empty",
+			data: "WEBVTT\n",
+		},
+		{
+			name:    "invalid time",
+			data:    "WEBVTT\n\n00:xx.000 --> 00:01.000\nBroken\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCues([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("parseCues succeeded, want an error")
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("parseCues: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCues = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkCues(t *testing.T) {
+	cue := func(start, end float64, text string) Cue {
+		return Cue{
+			Start: time.Duration(start * float64(time.Second)),
+			End:   time.Duration(end * float64(time.Second)),
+			Text:  text,
+		}
+	}
+	segment := func(start, end float64, text string) Frame {
+		return Frame{
+			Kind:        KindSpeech,
+			Timestamp:   time.Duration(start * float64(time.Second)),
+			End:         time.Duration(end * float64(time.Second)),
+			Description: text,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		cues   []Cue
+		window time.Duration
+		want   []Frame
+	}{
+		{
+			name:   "no cues",
+			window: 10 * time.Second,
+		},
+		{
+			name:   "one window",
+			cues:   []Cue{cue(0, 2, "a"), cue(2, 4, "b"), cue(9, 12, "c")},
+			window: 10 * time.Second,
+			want:   []Frame{segment(0, 12, "a b c")},
+		},
+		{
+			name:   "split at the window",
+			cues:   []Cue{cue(0, 2, "a"), cue(10, 12, "b"), cue(15, 16, "c"), cue(21, 22, "d")},
+			window: 10 * time.Second,
+			want:   []Frame{segment(0, 2, "a"), segment(10, 16, "b c"), segment(21, 22, "d")},
+		},
+		{
+			name:   "overlapping cues",
+			cues:   []Cue{cue(0, 8, "a"), cue(1, 3, "b")},
+			window: 10 * time.Second,
+			want:   []Frame{segment(0, 8, "a b")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ChunkCues(tt.cues, tt.window)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ChunkCues = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
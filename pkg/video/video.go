@@ -1,6 +1,8 @@
 package video
 
 import (
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"fmt"
 	"io"
@@ -8,9 +10,8 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strconv"
-	"strings"
-	"time"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
 )
 
 type Video struct {
@@ -18,63 +19,89 @@ type Video struct {
 	Path           string
 	Frames         []Frame
 	ProcessingPath string
+	AudioPath      string
+	WorkDir        string
 }
 
-func New(path string) (*Video, error) {
+func New(path string, workDir string) (*Video, error) {
 	id, err := hash(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash video: %w", err)
 	}
 
 	return &Video{
-		ID:   fmt.Sprintf("%x-%s", id, filepath.Base(path)),
-		Path: path,
+		ID:      fmt.Sprintf("%x", id),
+		Path:    path,
+		WorkDir: workDir,
 	}, nil
 }
 
 func (v *Video) Cleanup() error {
-	if v.ProcessingPath == "" {
-		return nil
+	for _, path := range []string{v.ProcessingPath, v.AudioPath} {
+		if path == "" {
+			continue
+		}
+
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
 	}
 
-	return os.RemoveAll(v.ProcessingPath)
+	return nil
 }
 
-func (v *Video) Extract(interval int) error {
-	log.Printf("starting frame extraction for video ID: %s with interval: %d seconds", v.ID, interval)
+func (v *Video) Extract(ctx context.Context, cfg *config.Config) error {
+	inputArgs, filter, err := samplingArgs(cfg)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Debug {
+		log.Printf("starting frame extraction for video ID: %s with filter: %s", v.ID, filter)
+	}
 
-	v.ProcessingPath = filepath.Join(os.TempDir(), "llm-video-analyze", v.ID)
+	v.ProcessingPath = filepath.Join(v.WorkDir, "frames", v.ID)
 	if err := os.MkdirAll(v.ProcessingPath, 0755); err != nil {
 		return fmt.Errorf("failed to create temp dir: %w", err)
 	}
-	log.Printf("created temporary directory: %s", v.ProcessingPath)
 
-	cmd := exec.Command(
-		"ffmpeg",
+	args := append(inputArgs,
 		"-i", v.Path,
-		"-vf", fmt.Sprintf("fps=1/%d", interval),
-		"-frame_pts", "1",
+		"-vf", filter,
+		"-fps_mode", "vfr",
 		filepath.Join(v.ProcessingPath, "frame_%05d.png"),
 	)
 
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	cmd.Stderr = &stderr
+
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("ffmpeg error: %w", err)
 	}
 
-	frames, _ := filepath.Glob(filepath.Join(v.ProcessingPath, "frame_*.png"))
+	timestamps := parseShowinfo(stderr.Bytes())
 
-	log.Printf("found %d frames in processing path: %s", len(frames), v.ProcessingPath)
+	frames, _ := filepath.Glob(filepath.Join(v.ProcessingPath, "frame_*.png"))
 
 	v.Frames = make([]Frame, len(frames))
 	for i, f := range frames {
+		ts, ok := timestamps[i]
+		if !ok {
+			return fmt.Errorf("no timestamp reported for frame %s", f)
+		}
+
 		v.Frames[i] = Frame{
+			Kind:      KindVisual,
 			Path:      f,
-			Timestamp: parseTimestamp(f, interval),
+			Timestamp: ts,
+			End:       ts,
 		}
-		log.Printf("processed frame %d: %s with timestamp: %v", i, f, v.Frames[i].Timestamp)
 	}
 
-	log.Printf("completed extraction for video ID: %s, total frames: %d", v.ID, len(v.Frames))
+	if cfg.Debug {
+		log.Printf("completed extraction for video ID: %s, total frames: %d", v.ID, len(v.Frames))
+	}
 
 	return nil
 }
@@ -93,16 +120,3 @@ func hash(path string) ([]byte, error) {
 
 	return h.Sum(nil), nil
 }
-
-func parseTimestamp(path string, interval int) time.Duration {
-	filename := filepath.Base(path)
-
-	if parts := strings.Split(filename, "_"); len(parts) > 1 {
-		timePart := strings.TrimSuffix(parts[1], filepath.Ext(parts[1]))
-		if secs, err := strconv.ParseInt(timePart, 10, 64); err == nil {
-			return time.Duration(int(secs)*interval) * time.Second
-		}
-	}
-
-	return 0
-}
@@ -0,0 +1,64 @@
+package video
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
+)
+
+// WebDownloader fetches http(s) URLs of hosts without a source of their own.
+// They go through yt-dlp, which understands the pages of most video sites,
+// unless the URL serves a video file, which is then fetched over plain HTTP.
+type WebDownloader struct {
+	*YouTubeDownloader
+	http *HTTPDownloader
+}
+
+var (
+	_ MetadataSource = (*WebDownloader)(nil)
+	_ SubtitleSource = (*WebDownloader)(nil)
+)
+
+func init() {
+	for _, scheme := range []string{"http", "https"} {
+		RegisterSource(scheme, "", func(cfg *config.Config, tempDir string) Downloader {
+			return &WebDownloader{
+				YouTubeDownloader: newYtDlp(cfg, tempDir),
+				http:              NewHTTPDownloader(tempDir),
+			}
+		})
+	}
+}
+
+func (wd *WebDownloader) Download(ctx context.Context, url string) (string, error) {
+	if wd.servesVideo(ctx, url) {
+		return wd.http.Download(ctx, url)
+	}
+
+	return wd.YouTubeDownloader.Download(ctx, url)
+}
+
+// servesVideo reports whether a HEAD request for url answers with a video
+// Content-Type. Any failure leaves the URL to yt-dlp.
+func (wd *WebDownloader) servesVideo(ctx context.Context, url string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return false
+	}
+
+	rep, err := wd.http.Client.Do(req)
+	if err != nil {
+		return false
+	}
+	rep.Body.Close()
+
+	if rep.StatusCode != http.StatusOK {
+		return false
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(rep.Header.Get("Content-Type"))
+	return strings.HasPrefix(mediaType, "video/")
+}
@@ -0,0 +1,115 @@
+package video
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
+)
+
+type YouTubeDownloader struct {
+	TempDir string
+	// SubtitleLangs selects the caption languages to fetch, in yt-dlp's
+	// --sub-langs syntax. Captions are skipped when it is empty.
+	SubtitleLangs string
+	// Debug logs metadata that could not be parsed instead of dropping it
+	// silently.
+	Debug     bool
+	info      *Metadata
+	subtitles string
+}
+
+var (
+	_ MetadataSource = (*YouTubeDownloader)(nil)
+	_ SubtitleSource = (*YouTubeDownloader)(nil)
+)
+
+func init() {
+	for _, scheme := range []string{"http", "https"} {
+		for _, host := range []string{"youtube.com", "youtu.be"} {
+			RegisterSource(scheme, host, func(cfg *config.Config, tempDir string) Downloader {
+				return newYtDlp(cfg, tempDir)
+			})
+		}
+	}
+}
+
+func NewYouTubeDownloader(tempDir string) *YouTubeDownloader {
+	return &YouTubeDownloader{TempDir: tempDir}
+}
+
+// newYtDlp returns a yt-dlp downloader set up from cfg.
+func newYtDlp(cfg *config.Config, tempDir string) *YouTubeDownloader {
+	d := NewYouTubeDownloader(tempDir)
+	d.Debug = cfg.Debug
+	if cfg.Subtitles {
+		d.SubtitleLangs = cfg.SubtitleLangs
+	}
+	return d
+}
+
+func (yd *YouTubeDownloader) Download(ctx context.Context, url string) (string, error) {
+	if err := os.MkdirAll(yd.TempDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create temp dir: %w", err)
+	}
+
+	tempFile := filepath.Join(yd.TempDir, "download.mp4")
+
+	var stdout bytes.Buffer
+	args := []string{"--print-json", "-o", tempFile}
+	if yd.SubtitleLangs != "" {
+		args = append(args,
+			"--write-subs",
+			"--write-auto-subs",
+			"--sub-langs", yd.SubtitleLangs,
+			"--sub-format", "vtt/srt/best",
+		)
+	}
+
+	cmd := exec.CommandContext(ctx, "yt-dlp", append(args, url)...)
+	cmd.Stdout = &stdout
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("download failed: %w", err)
+	}
+
+	info, err := parseYtDlpInfo(stdout.Bytes())
+	if err != nil && yd.Debug {
+		log.Printf("ignoring video info: %v", err)
+	}
+	yd.info = info
+	yd.subtitles = findSubtitles(tempFile)
+
+	if _, err := os.Stat(tempFile); err != nil {
+		return "", fmt.Errorf("downloaded file not found: %w", err)
+	}
+
+	return tempFile, nil
+}
+
+func (yd *YouTubeDownloader) Metadata() *Metadata {
+	return yd.info
+}
+
+func (yd *YouTubeDownloader) Subtitles() string {
+	return yd.subtitles
+}
+
+// findSubtitles returns the first caption file yt-dlp wrote next to video.
+func findSubtitles(video string) string {
+	base := strings.TrimSuffix(video, filepath.Ext(video))
+	for _, ext := range []string{"vtt", "srt"} {
+		matches, _ := filepath.Glob(base + ".*." + ext)
+		if len(matches) > 0 {
+			return matches[0]
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,129 @@
+package whisper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/config"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/ratelimit"
+	"github.com/mahyarmirrashed/llm-video-analyzer/pkg/video"
+)
+
+// Transcribe sends an audio file to the Whisper-compatible endpoint at
+// cfg.TranscriptionURL, such as an OpenAI-style /v1/audio/transcriptions or
+// whisper.cpp's /inference, and returns the transcript segments shifted by
+// offset, the position of the audio within its video.
+func Transcribe(ctx context.Context, cfg *config.Config, path string, offset time.Duration) ([]video.Cue, error) {
+	body, contentType, err := form(cfg, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if u, err := url.Parse(cfg.TranscriptionURL); err == nil {
+		if err := ratelimit.Wait(ctx, u.Host, cfg.RateLimit); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", cfg.TranscriptionURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	client := &http.Client{Timeout: 10 * time.Minute}
+
+	rep, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("api request failed: %w", err)
+	}
+	defer rep.Body.Close()
+
+	if rep.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(rep.Body)
+		return nil, fmt.Errorf("api error: %s (%d)", string(body), rep.StatusCode)
+	}
+
+	var res struct {
+		Text     string `json:"text"`
+		Segments []struct {
+			Start float64 `json:"start"`
+			End   float64 `json:"end"`
+			Text  string  `json:"text"`
+		} `json:"segments"`
+	}
+	if err := json.NewDecoder(rep.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("failed to decode transcription response: %w", err)
+	}
+
+	var cues []video.Cue
+	for _, seg := range res.Segments {
+		text := strings.TrimSpace(seg.Text)
+		if text == "" {
+			continue
+		}
+
+		cues = append(cues, video.Cue{
+			Start: offset + seconds(seg.Start),
+			End:   offset + seconds(seg.End),
+			Text:  text,
+		})
+	}
+
+	// servers that ignore verbose_json only return the text
+	if len(cues) == 0 && strings.TrimSpace(res.Text) != "" {
+		cues = append(cues, video.Cue{Start: offset, End: offset, Text: strings.TrimSpace(res.Text)})
+	}
+
+	return cues, nil
+}
+
+func form(cfg *config.Config, path string) (io.Reader, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open audio: %w", err)
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	part, err := w.CreateFormFile("file", filepath.Base(path))
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := io.Copy(part, f); err != nil {
+		return nil, "", fmt.Errorf("failed to read audio: %w", err)
+	}
+
+	fields := map[string]string{
+		"model":                     cfg.TranscriptionModel,
+		"response_format":           "verbose_json",
+		"timestamp_granularities[]": "segment",
+	}
+	for name, value := range fields {
+		if err := w.WriteField(name, value); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return &buf, w.FormDataContentType(), nil
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}